@@ -0,0 +1,95 @@
+package color
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+)
+
+func NewColorCommand() *cobra.Command {
+	var k int
+	cmd := &cobra.Command{
+		Use:   "color <path> -k <colors>",
+		Short: "Colors a graph given in dimacs format",
+		Long: `Colors the vertices of a graph given in DIMACS graph coloring format with at
+most k colors, so that no edge joins two vertices of the same color. For instance:
+c
+c a triangle
+c header: p edge <number of vertices> <number of edges>
+p edge 3 3
+c edges join two vertices, numbered from 1
+e 1 2
+e 2 3
+e 1 3
+
+When there is no such coloring, a minimal subgraph that cannot be colored
+either is reported instead, as the edges that make it up along with the line of
+the file they were read from: removing any of them makes the subgraph colorable.
+`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := os.Stat(args[0]); errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("file (%s) not found", args[0])
+			}
+			if k < 1 {
+				return fmt.Errorf("invalid number of colors (%d): must be at least 1", k)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return color(cmd.OutOrStdout(), args[0], k)
+		},
+	}
+	cmd.Flags().IntVarP(&k, "colors", "k", 3, "number of colors")
+	return cmd
+}
+
+func color(w io.Writer, path string, k int) error {
+	// open graph file
+	graphFile, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening graph file (%s): %w", path, err)
+	}
+	defer graphFile.Close()
+
+	g, err := ParseGraph(graphFile)
+	if err != nil {
+		return fmt.Errorf("error parsing graph file (%s): %w", path, err)
+	}
+
+	// build solver
+	so, err := solver.New()
+	if err != nil {
+		return err
+	}
+
+	// get coloring
+	colors, err := Color(so, g, k)
+	var unsat deppy.NotSatisfiable
+	if errors.As(err, &unsat) {
+		edges, err := MinimalUnsatisfiableSubgraph(so, g, k, unsat)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "no coloring found with %d colors, minimal subgraph that cannot be colored:\n", k)
+		for _, edge := range edges {
+			fmt.Fprintf(w, "  %s\n", edge)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "coloring found with %d colors:\n", k)
+	for v, c := range colors {
+		fmt.Fprintf(w, "%d = %d\n", v+1, c)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package color
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+)
+
+// Graph is an undirected graph described in the DIMACS graph
+// coloring format, whose vertices are numbered from 1.
+// see: https://mat.gsia.cmu.edu/COLOR/general/ccformat.ps
+type Graph struct {
+	vertices int
+	edges    []Edge
+}
+
+// Edge joins two vertices of a Graph, and records the line of the
+// input it was read from.
+type Edge struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	Line int `json:"line"`
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("line %d: e %d %d", e.Line, e.From, e.To)
+}
+
+// Vertices returns the number of vertices of the graph.
+func (g *Graph) Vertices() int {
+	return g.vertices
+}
+
+// Edges returns the edges of the graph, in the order they were read.
+func (g *Graph) Edges() []Edge {
+	return g.edges
+}
+
+// ParseGraph reads a graph in DIMACS graph coloring format, for
+// instance
+//
+//	c a triangle
+//	p edge 3 3
+//	e 1 2
+//	e 2 3
+//	e 1 3
+//
+// Lines starting with 'c' are comments, and the header is followed
+// by one line per edge. Edges listed more than once, in either
+// direction, are only kept the first time. Lines that describe
+// vertices rather than edges, such as the 'n' lines of weighted
+// graphs, are ignored. The number of edges declared by the header
+// is not checked, as many benchmarks list every edge twice.
+func ParseGraph(r io.Reader) (*Graph, error) {
+	g := &Graph{vertices: -1}
+	seen := map[[2]int]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		switch fields[0] {
+		case "p":
+			if g.vertices >= 0 {
+				return nil, fmt.Errorf("line %d: duplicate header", line)
+			}
+			if len(fields) != 4 || (fields[1] != "edge" && fields[1] != "col") {
+				return nil, fmt.Errorf("line %d: invalid header, expected 'p edge <vertices> <edges>'", line)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("line %d: invalid number of vertices (%s)", line, fields[2])
+			}
+			g.vertices = n
+		case "e":
+			if g.vertices < 0 {
+				return nil, fmt.Errorf("line %d: edge before the header", line)
+			}
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("line %d: invalid edge, expected 'e <vertex> <vertex>'", line)
+			}
+			var ends [2]int
+			for i, field := range fields[1:] {
+				v, err := strconv.Atoi(field)
+				if err != nil || v < 1 || v > g.vertices {
+					return nil, fmt.Errorf("line %d: invalid vertex (%s): must be between 1 and %d", line, field, g.vertices)
+				}
+				ends[i] = v
+			}
+			key := ends
+			if key[0] > key[1] {
+				key[0], key[1] = key[1], key[0]
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			g.edges = append(g.edges, Edge{From: ends[0], To: ends[1], Line: line})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if g.vertices < 0 {
+		return nil, errors.New("invalid format: missing header 'p edge <vertices> <edges>'")
+	}
+	return g, nil
+}
+
+// VertexIdentifier returns the Identifier of the Variable of the
+// given vertex.
+func VertexIdentifier(vertex int) deppy.Identifier {
+	return deppy.IdentifierFromString(fmt.Sprintf("v%d", vertex))
+}
+
+// ColorIdentifier returns the Identifier of the Variable that gives
+// the given color to the given vertex.
+func ColorIdentifier(vertex int, color int) deppy.Identifier {
+	return deppy.IdentifierFromString(fmt.Sprintf("v%d/c%d", vertex, color))
+}
+
+// LineLabel is the metadata label that holds the line of the input an
+// edge was read from.
+const LineLabel = "line"
+
+// GenerateProblem returns the problem of coloring the vertices of the
+// graph with at most k colors, so that no edge joins two vertices of
+// the same color. Every vertex is a Mandatory Variable with a
+// Dependency on one Variable per color, at most one of which can be
+// selected, and every edge makes the Variables of the same color of
+// its ends Conflict. The constraints of an edge carry its line as
+// metadata, so that unsatisfiable cores can be mapped back to the
+// edges of the input.
+func GenerateProblem(g *Graph, k int) deppy.Problem {
+	conflicts := make([][]deppy.Constraint, g.vertices*k)
+	for _, edge := range g.edges {
+		metadata := deppy.Metadata{Labels: map[string]string{LineLabel: strconv.Itoa(edge.Line)}}
+		for c := 1; c <= k; c++ {
+			i := (edge.From-1)*k + c - 1
+			conflicts[i] = append(conflicts[i], constraint.WithMetadata(constraint.Conflict(ColorIdentifier(edge.To, c)), metadata))
+		}
+	}
+
+	problem := deppy.Problem{Variables: make([]deppy.Variable, 0, g.vertices*(k+1))}
+	for v := 1; v <= g.vertices; v++ {
+		colors := make([]deppy.Identifier, k)
+		for c := 1; c <= k; c++ {
+			colors[c-1] = ColorIdentifier(v, c)
+			problem.Variables = append(problem.Variables, input.NewSimpleVariable(colors[c-1], conflicts[(v-1)*k+c-1]...))
+		}
+		problem.Variables = append(problem.Variables, input.NewSimpleVariable(VertexIdentifier(v),
+			constraint.Mandatory(),
+			constraint.Dependency(colors...),
+			constraint.AtMost(1, colors...),
+		))
+	}
+	return problem
+}
+
+// Color returns the color, from 1 to k, of every vertex of the graph
+// in a coloring where no edge joins two vertices of the same color,
+// indexed by vertex minus one. Colors with a lower number are
+// preferred.
+func Color(so *solver.Solver, g *Graph, k int) ([]int, error) {
+	selection, err := so.SolveProblem(GenerateProblem(g, k))
+	if err != nil {
+		return nil, err
+	}
+	selected := map[deppy.Identifier]struct{}{}
+	for _, variable := range selection {
+		selected[variable.Identifier()] = struct{}{}
+	}
+	colors := make([]int, g.vertices)
+	for v := 1; v <= g.vertices; v++ {
+		for c := 1; c <= k; c++ {
+			if _, ok := selected[ColorIdentifier(v, c)]; ok {
+				colors[v-1] = c
+				break
+			}
+		}
+	}
+	return colors, nil
+}
+
+// MinimalUnsatisfiableSubgraph returns a set of edges of the graph
+// that cannot be colored with k colors, but whose every proper subset
+// can, starting from the edges of the unsatisfiable core returned by
+// Color. Every edge of the set is removed in turn, and left out if the
+// remaining edges still cannot be colored, in which case the core of
+// that attempt further narrows the set down.
+func MinimalUnsatisfiableSubgraph(so *solver.Solver, g *Graph, k int, unsat deppy.NotSatisfiable) ([]Edge, error) {
+	edges := coreEdges(g, unsat)
+	for i := 0; i < len(edges); {
+		candidate := append(append([]Edge(nil), edges[:i]...), edges[i+1:]...)
+		_, err := so.SolveProblem(GenerateProblem(&Graph{vertices: g.vertices, edges: candidate}, k))
+		var core deppy.NotSatisfiable
+		if errors.As(err, &core) {
+			edges = coreEdges(&Graph{edges: candidate}, core)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the edge is needed
+		i++
+	}
+	return edges, nil
+}
+
+// coreEdges returns the edges of the graph whose constraints appear
+// in an unsatisfiable core, in the order of the graph.
+func coreEdges(g *Graph, unsat deppy.NotSatisfiable) []Edge {
+	lines := map[int]struct{}{}
+	for _, applied := range unsat {
+		line, err := strconv.Atoi(applied.Metadata().Labels[LineLabel])
+		if err == nil {
+			lines[line] = struct{}{}
+		}
+	}
+	var edges []Edge
+	for _, edge := range g.edges {
+		if _, ok := lines[edge.Line]; ok {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
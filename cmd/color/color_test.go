@@ -0,0 +1,155 @@
+package color_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+
+	"github.com/operator-framework/deppy/cmd/color"
+)
+
+func TestColor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Color Suite")
+}
+
+func load(path string) *color.Graph {
+	f, err := os.Open(path)
+	Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+	g, err := color.ParseGraph(f)
+	Expect(err).ToNot(HaveOccurred())
+	return g
+}
+
+var _ = Describe("Graph", func() {
+	It("should parse edges and skip duplicates", func() {
+		g := load("testdata/k4-tail.col")
+		Expect(g.Vertices()).To(Equal(6))
+		Expect(g.Edges()).To(HaveLen(8))
+		Expect(g.Edges()[0]).To(Equal(color.Edge{From: 1, To: 2, Line: 5}))
+		Expect(g.Edges()[0].String()).To(Equal("line 5: e 1 2"))
+	})
+	It("should ignore vertex lines", func() {
+		g, err := color.ParseGraph(strings.NewReader("p col 2 1\nn 1 3\ne 1 2\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.Edges()).To(Equal([]color.Edge{{From: 1, To: 2, Line: 3}}))
+	})
+	It("should reject malformed graphs", func() {
+		_, err := color.ParseGraph(strings.NewReader("e 1 2\n"))
+		Expect(err).To(MatchError("line 1: edge before the header"))
+		_, err = color.ParseGraph(strings.NewReader("p cnf 2 1\n"))
+		Expect(err).To(MatchError("line 1: invalid header, expected 'p edge <vertices> <edges>'"))
+		_, err = color.ParseGraph(strings.NewReader("p edge 2 1\ne 1 3\n"))
+		Expect(err).To(MatchError("line 2: invalid vertex (3): must be between 1 and 2"))
+		_, err = color.ParseGraph(strings.NewReader("c no header\n"))
+		Expect(err).To(MatchError("invalid format: missing header 'p edge <vertices> <edges>'"))
+	})
+})
+
+var _ = Describe("Color", func() {
+	var so *solver.Solver
+	BeforeEach(func() {
+		var err error
+		so, err = solver.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should color a graph with enough colors", func() {
+		g := load("testdata/myciel3.col")
+		colors, err := color.Color(so, g, 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(colors).To(HaveLen(11))
+		for _, c := range colors {
+			Expect(c).To(BeNumerically(">=", 1))
+			Expect(c).To(BeNumerically("<=", 4))
+		}
+		for _, edge := range g.Edges() {
+			Expect(colors[edge.From-1]).ToNot(Equal(colors[edge.To-1]))
+		}
+	})
+	It("should report the whole of a critical graph", func() {
+		// the Grötzsch graph needs 4 colors, but any of its edges
+		// can be colored with 3 colors once removed
+		g := load("testdata/myciel3.col")
+		_, err := color.Color(so, g, 3)
+		var unsat deppy.NotSatisfiable
+		Expect(errors.As(err, &unsat)).To(BeTrue())
+		edges, err := color.MinimalUnsatisfiableSubgraph(so, g, 3, unsat)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(edges).To(Equal(g.Edges()))
+	})
+	It("should narrow an unsatisfiable graph down to a minimal subgraph", func() {
+		g := load("testdata/k4-tail.col")
+		_, err := color.Color(so, g, 3)
+		var unsat deppy.NotSatisfiable
+		Expect(errors.As(err, &unsat)).To(BeTrue())
+		edges, err := color.MinimalUnsatisfiableSubgraph(so, g, 3, unsat)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(edges).To(ConsistOf(
+			color.Edge{From: 1, To: 2, Line: 5},
+			color.Edge{From: 1, To: 3, Line: 9},
+			color.Edge{From: 1, To: 4, Line: 13},
+			color.Edge{From: 2, To: 3, Line: 15},
+			color.Edge{From: 2, To: 4, Line: 17},
+			color.Edge{From: 3, To: 4, Line: 19},
+		))
+	})
+	It("should find an odd cycle when coloring with 2 colors", func() {
+		g := load("testdata/myciel3.col")
+		_, err := color.Color(so, g, 2)
+		var unsat deppy.NotSatisfiable
+		Expect(errors.As(err, &unsat)).To(BeTrue())
+		edges, err := color.MinimalUnsatisfiableSubgraph(so, g, 2, unsat)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(edges) % 2).To(Equal(1))
+		degree := map[int]int{}
+		for _, edge := range edges {
+			degree[edge.From]++
+			degree[edge.To]++
+		}
+		for _, d := range degree {
+			Expect(d).To(Equal(2))
+		}
+	})
+})
+
+var _ = Describe("Color Command", func() {
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := color.NewColorCommand()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	It("should print a coloring", func() {
+		out, err := run("testdata/k4-tail.col", "-k", "4")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("coloring found with 4 colors:\n1 = "))
+		Expect(strings.Count(out, "\n")).To(Equal(7))
+	})
+	It("should print a minimal subgraph that cannot be colored", func() {
+		out, err := run("testdata/k4-tail.col", "-k", "3")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("no coloring found with 3 colors, minimal subgraph that cannot be colored:\n"))
+		Expect(out).To(ContainSubstring("  line 19: e 3 4\n"))
+		Expect(out).ToNot(ContainSubstring("e 5 6"))
+	})
+	It("should reject invalid arguments", func() {
+		_, err := run("testdata/k4-tail.col", "-k", "0")
+		Expect(err).To(MatchError("invalid number of colors (0): must be at least 1"))
+		_, err = run("testdata/missing.col")
+		Expect(err).To(MatchError("file (testdata/missing.col) not found"))
+	})
+})
@@ -1,18 +1,58 @@
 package dimacs
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
 	"github.com/operator-framework/deppy/pkg/deppy/solver"
 )
 
+// Output formats of the solve command.
+const (
+	// OutputText lists every variable along with its value.
+	OutputText = "text"
+	// OutputCompetition follows the conventions of the SAT
+	// competition: a status line starting with "s", model lines
+	// starting with "v", and exit code 10 or 20.
+	OutputCompetition = "competition"
+	// OutputJSON writes a single JSON object with the status and
+	// either the model or the unsatisfiable core.
+	OutputJSON = "json"
+)
+
+// Exit codes reported by the competition output format.
+const (
+	ExitSatisfiable   = 10
+	ExitUnsatisfiable = 20
+	// ExitOptimumFound is reported for weighted MaxSAT problems, as
+	// in the MaxSAT evaluations.
+	ExitOptimumFound = 30
+)
+
+// ExitStatus is returned by the solve command to request that the
+// process exits with the given code, without reporting an error.
+type ExitStatus int
+
+func (e ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (e ExitStatus) ExitCode() int {
+	return int(e)
+}
+
 func NewDimacsCommand() *cobra.Command {
-	return &cobra.Command{
+	var output, corePath string
+	cmd := &cobra.Command{
 		Use:   "solve <path>",
 		Short: "Solves a sat problem given in dimacs format",
 		Long: `Solves a sat problem given in dimacs format. For instance:
@@ -25,21 +65,60 @@ c 0 (zero) is not a valid literal
 1 2 0
 1 -2 0
 c cnf: (1 or 2) and (1 and not 2)
+
+Clauses may span several lines, and the file may be compressed with gzip or bzip2.
+
+Files with a 'p wcnf' header, or named *.wcnf, hold weighted MaxSAT problems:
+every clause starts with its weight, or 'h' for hard clauses, and the solution
+minimizes the total weight of the soft clauses it falsifies.
+
+With --output competition, the outcome is reported as in the SAT competition:
+"s SATISFIABLE" followed by "v" lines listing the model, and exit code 10,
+or "s UNSATISFIABLE" and exit code 20. Optimal solutions to weighted MaxSAT
+problems are reported as "s OPTIMUM FOUND", "o <cost>" and "v" lines, with exit
+code 30.
+
+Files with a 'p inccnf' header hold incremental problems, where lines starting
+with 'a' list assumption literals up to a terminating 0. The clauses preceding
+each such cube are solved under its assumptions, reusing the same solver
+instance, and the outcome of every cube is reported in turn. In the competition
+format, the exit code is that of the last cube; in the json format, one object is
+written per line for each cube. With --output json, a JSON object
+holding the status and either the model or the unsatisfiable core is written.
+
+When there is no solution, the clauses of an unsatisfiable core are reported
+along with the line of the file they start on, and the assumptions of a cube as
+unit clauses on the line of the cube. In the competition format, they are
+written as "c core" comments. With --core, the core is also written to the given
+file in DIMACS format, as a smaller problem to debug the encoding with.
 `,
 		Args: cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if _, err := os.Stat(args[0]); errors.Is(err, os.ErrNotExist) {
 				return fmt.Errorf("file (%s) not found", args[0])
 			}
-			return nil
+			switch output {
+			case OutputText, OutputCompetition, OutputJSON:
+				return nil
+			}
+			return fmt.Errorf("invalid output format (%s): must be one of %s, %s, %s", output, OutputText, OutputCompetition, OutputJSON)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return solve(args[0])
+			err := solve(cmd.OutOrStdout(), args[0], output, corePath)
+			var status ExitStatus
+			if errors.As(err, &status) {
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+			}
+			return err
 		},
 	}
+	cmd.Flags().StringVarP(&output, "output", "o", OutputText, fmt.Sprintf("output format, one of %s, %s, %s", OutputText, OutputCompetition, OutputJSON))
+	cmd.Flags().StringVar(&corePath, "core", "", "file to write the unsatisfiable core to, in dimacs format")
+	return cmd
 }
 
-func solve(path string) error {
+func solve(w io.Writer, path string, output string, corePath string) error {
 	// open dimacs file
 	dimacsFile, err := os.Open(path)
 	if err != nil {
@@ -47,7 +126,11 @@ func solve(path string) error {
 	}
 	defer dimacsFile.Close()
 
-	dimacs, err := NewDimacs(dimacsFile)
+	parse := NewDimacs
+	if isWCNF(path) {
+		parse = NewWCNF
+	}
+	dimacs, err := parse(dimacsFile)
 	if err != nil {
 		return fmt.Errorf("error parsing dimacs file (%s): %w", path, err)
 	}
@@ -58,26 +141,232 @@ func solve(path string) error {
 		return err
 	}
 
+	if dimacs.Incremental() {
+		return solveIncremental(w, so, dimacs, output, corePath)
+	}
+
 	// get solution
-	vars, err := GenerateVariables(dimacs)
+	var result outcome
+	if dimacs.Weighted() {
+		result.selection, result.cost, result.err = SolveOptimal(so, dimacs)
+	} else {
+		problem, err := GenerateProblem(dimacs)
+		if err != nil {
+			return fmt.Errorf("error generating problem: %s", err)
+		}
+		result.selection, result.err = so.SolveProblem(problem)
+	}
+	if result.err != nil && !errors.As(result.err, &result.unsat) {
+		return result.err
+	}
+	result.core = Core(result.unsat)
+	if result.err != nil && corePath != "" {
+		if err := writeCoreFile(corePath, dimacs, result.core); err != nil {
+			return err
+		}
+	}
+	return result.write(w, dimacs, output)
+}
+
+// writeCoreFile writes an unsatisfiable core to the file at the given
+// path, in DIMACS format.
+func writeCoreFile(path string, dimacs *Dimacs, core []CoreClause) error {
+	f, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("error generating variables: %s", err)
+		return fmt.Errorf("error creating core file (%s): %w", path, err)
+	}
+	if err := WriteCore(f, dimacs, core); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing core file (%s): %w", path, err)
 	}
-	selection, err := so.Solve(vars)
+	return f.Close()
+}
+
+// solveIncremental solves the clauses preceding each assumption cube
+// of an incremental problem under the assumptions of the cube, with
+// the same solver instance, and writes the outcome for each cube. In
+// the competition output format, the exit status is that of the last
+// cube, and the core written to corePath, if any, is that of the last
+// unsatisfiable cube.
+func solveIncremental(w io.Writer, so *solver.Solver, dimacs *Dimacs, output string, corePath string) error {
+	problem, err := GenerateProblem(&Dimacs{variables: dimacs.variables})
 	if err != nil {
-		fmt.Printf("no solution found: %s\n", err)
-	} else {
-		selected := map[deppy.Identifier]struct{}{}
-		for _, variable := range selection {
-			selected[variable.Identifier()] = struct{}{}
+		return fmt.Errorf("error generating problem: %s", err)
+	}
+	inc, err := so.Incremental(problem.Variables)
+	if err != nil {
+		return err
+	}
+
+	var status error
+	added := 0
+	for i, cube := range dimacs.cubes {
+		var clauses []deppy.Constraint
+		for ; added < cube.Clauses; added++ {
+			clauses = append(clauses, dimacs.hardClause(added, dimacs.clauses[added]))
+		}
+		if err := inc.AddConstraints(clauses...); err != nil {
+			return err
+		}
+		assumptions := make([]deppy.Constraint, len(cube.Literals))
+		for j, literal := range literals(cube.Literals) {
+			assumptions[j] = atLine(constraint.Clause(literal), cube.Line)
 		}
 
-		fmt.Println("solution found:")
-		for _, variable := range vars {
-			_, ok := selected[variable.Identifier()]
-			fmt.Printf("%s = %t\n", variable.Identifier(), ok)
+		result := outcome{cube: i + 1, assumptions: cube.Literals}
+		result.selection, result.err = inc.Solve(assumptions...)
+		if result.err != nil && !errors.As(result.err, &result.unsat) {
+			return result.err
+		}
+		result.core = Core(result.unsat)
+		if result.err != nil && corePath != "" {
+			if err := writeCoreFile(corePath, dimacs, result.core); err != nil {
+				return err
+			}
+		}
+		status = result.write(w, dimacs, output)
+		var exit ExitStatus
+		if status != nil && !errors.As(status, &exit) {
+			return status
 		}
 	}
+	return status
+}
+
+// outcome is the outcome of solving a problem, or a single cube of
+// an incremental problem, as written by the solve command.
+type outcome struct {
+	selection []deppy.Variable
+	cost      int
+	err       error
+	unsat     deppy.NotSatisfiable
+	core      []CoreClause
+	// cube is the 1-based index of the assumption cube of an
+	// incremental problem, or 0.
+	cube        int
+	assumptions []int
+}
+
+func (o outcome) write(w io.Writer, dimacs *Dimacs, output string) error {
+	switch output {
+	case OutputCompetition:
+		return o.writeCompetition(w, dimacs)
+	case OutputJSON:
+		return o.writeJSON(w, dimacs)
+	}
 
+	if o.cube > 0 {
+		fmt.Fprintf(w, "cube %d:", o.cube)
+		for _, lit := range o.assumptions {
+			fmt.Fprintf(w, " %d", lit)
+		}
+		fmt.Fprintln(w)
+	}
+	if o.err != nil {
+		fmt.Fprintln(w, "no solution found, unsatisfiable core:")
+		for _, clause := range o.core {
+			fmt.Fprintf(w, "  %s\n", clause)
+		}
+		return nil
+	}
+	selected := selectedSet(o.selection)
+	if dimacs.Weighted() {
+		fmt.Fprintf(w, "optimal solution found with cost %d:\n", o.cost)
+	} else {
+		fmt.Fprintln(w, "solution found:")
+	}
+	for _, id := range dimacs.variables {
+		_, ok := selected[deppy.IdentifierFromString(id)]
+		fmt.Fprintf(w, "%s = %t\n", id, ok)
+	}
 	return nil
 }
+
+// isWCNF reports whether the file at the given path is named like a
+// WCNF file, possibly compressed.
+func isWCNF(path string) bool {
+	name := filepath.Base(path)
+	for _, ext := range []string{".gz", ".bz2"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return filepath.Ext(name) == ".wcnf"
+}
+
+func selectedSet(selection []deppy.Variable) map[deppy.Identifier]struct{} {
+	selected := map[deppy.Identifier]struct{}{}
+	for _, variable := range selection {
+		selected[variable.Identifier()] = struct{}{}
+	}
+	return selected
+}
+
+// model returns the value of every variable of the problem as a
+// DIMACS literal, negative for false.
+func model(dimacs *Dimacs, selection []deppy.Variable) []int {
+	selected := selectedSet(selection)
+	lits := make([]int, len(dimacs.variables))
+	for i, id := range dimacs.variables {
+		lits[i] = i + 1
+		if _, ok := selected[deppy.IdentifierFromString(id)]; !ok {
+			lits[i] = -lits[i]
+		}
+	}
+	return lits
+}
+
+// maxLineLength bounds the length of "v" lines, as required by the
+// SAT competition.
+const maxLineLength = 80
+
+func (o outcome) writeCompetition(w io.Writer, dimacs *Dimacs) error {
+	if o.err != nil {
+		fmt.Fprintln(w, "s UNSATISFIABLE")
+		for _, clause := range o.core {
+			fmt.Fprintf(w, "c core %s\n", clause)
+		}
+		return ExitStatus(ExitUnsatisfiable)
+	}
+	status := ExitStatus(ExitSatisfiable)
+	if dimacs.Weighted() {
+		fmt.Fprintln(w, "s OPTIMUM FOUND")
+		fmt.Fprintf(w, "o %d\n", o.cost)
+		status = ExitOptimumFound
+	} else {
+		fmt.Fprintln(w, "s SATISFIABLE")
+	}
+	line := "v"
+	for _, lit := range append(model(dimacs, o.selection), 0) {
+		term := fmt.Sprintf(" %d", lit)
+		if len(line)+len(term) > maxLineLength {
+			fmt.Fprintln(w, line)
+			line = "v"
+		}
+		line += term
+	}
+	fmt.Fprintln(w, line)
+	return status
+}
+
+type jsonOutput struct {
+	Cube        int          `json:"cube,omitempty"`
+	Assumptions []int        `json:"assumptions,omitempty"`
+	Status      string       `json:"status"`
+	Cost        *int         `json:"cost,omitempty"`
+	Model       []int        `json:"model,omitempty"`
+	Core        []CoreClause `json:"core,omitempty"`
+}
+
+// writeJSON writes the outcome as a JSON object on a single line, so
+// that the outcomes of the cubes of an incremental problem form a
+// stream of JSON lines.
+func (o outcome) writeJSON(w io.Writer, dimacs *Dimacs) error {
+	out := jsonOutput{Cube: o.cube, Assumptions: o.assumptions, Status: "UNSATISFIABLE", Core: o.core}
+	if o.err == nil {
+		out.Status, out.Core, out.Model = "SATISFIABLE", nil, model(dimacs, o.selection)
+		if dimacs.Weighted() {
+			cost := o.cost
+			out.Status, out.Cost = "OPTIMUM FOUND", &cost
+		}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -2,12 +2,14 @@ package dimacs
 
 import (
 	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
-	"regexp"
+	"math"
 	"strconv"
-	"strings"
 )
 
 // Dimacs constrains the variables and clauses that make up
@@ -15,136 +17,415 @@ import (
 // see: https://logic.pdmi.ras.ru/~basolver/dimacs.html
 type Dimacs struct {
 	variables []string
-	clauses   []string
+	clauses   [][]int
+	// lines holds the line on which each clause starts.
+	lines []int
+	// weighted is set for weighted MaxSAT problems, whose soft
+	// clauses are held by soft.
+	weighted bool
+	soft     []WeightedClause
+	// incremental is set for incremental problems, whose
+	// assumption cubes are held by cubes.
+	incremental bool
+	cubes       []Cube
+}
+
+// WeightedClause is a soft clause of a weighted MaxSAT problem,
+// whose weight is incurred by any assignment that falsifies it.
+type WeightedClause struct {
+	Weight   int
+	Literals []int
 }
 
 func (d *Dimacs) Variables() []string {
 	return d.variables
 }
 
-func (d *Dimacs) Clauses() []string {
+// Clauses returns the clauses of the problem, each as a slice of
+// non-zero literals, where a negative literal -v stands for the
+// negation of variable v. The clauses of a weighted MaxSAT problem
+// are its hard clauses.
+func (d *Dimacs) Clauses() [][]int {
 	return d.clauses
 }
 
+// ClauseLines returns the line of the input on which each clause
+// returned by Clauses starts.
+func (d *Dimacs) ClauseLines() []int {
+	return d.lines
+}
+
+// Weighted reports whether the problem is a weighted MaxSAT problem,
+// read from a WCNF stream.
+func (d *Dimacs) Weighted() bool {
+	return d.weighted
+}
+
+// SoftClauses returns the soft clauses of a weighted MaxSAT problem.
+func (d *Dimacs) SoftClauses() []WeightedClause {
+	return d.soft
+}
+
+// Cube is a conjunction of literals to be assumed while solving the
+// clauses of an incremental problem that precede it.
+type Cube struct {
+	// Clauses is the number of clauses that precede the cube.
+	Clauses  int
+	Literals []int
+	// Line is the line of the input on which the cube starts.
+	Line int
+}
+
+// Incremental reports whether the problem is an incremental problem,
+// read from an iCNF stream.
+func (d *Dimacs) Incremental() bool {
+	return d.incremental
+}
+
+// Cubes returns the assumption cubes of an incremental problem, in
+// order.
+func (d *Dimacs) Cubes() []Cube {
+	return d.cubes
+}
+
+// SyntaxError describes a malformed DIMACS stream, along with the
+// position at which the problem was detected.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
 // NewDimacs creates a Dimacs struct with the values
-// parsed from the DIMACS formatted stream afforted by dimacsReader
+// parsed from the DIMACS formatted stream afforted by dimacsReader.
+// The stream is read one line at a time, and may be compressed with
+// gzip or bzip2. Clauses may span several lines, variables declared
+// in the header need not appear in any clause, the number of clauses
+// declared in the header need not match the number of clauses found,
+// and a line starting with '%' ends the problem, as in the SATLIB
+// benchmarks.
+//
+// A stream with a 'p wcnf <variables> <clauses> [<top>]' header is
+// read as a weighted MaxSAT problem, as described by NewWCNF. A
+// stream with a 'p inccnf' header is read as an incremental problem,
+// in which clauses are interleaved with assumption cubes: lines
+// starting with 'a' and listing literals up to a terminating 0. The
+// number of variables of an incremental problem is that of the
+// largest variable found in a clause or cube.
 func NewDimacs(dimacsReader io.Reader) (*Dimacs, error) {
-	reader := bufio.NewReader(dimacsReader)
+	return parse(dimacsReader, false)
+}
+
+// NewWCNF creates a Dimacs struct holding the weighted MaxSAT problem
+// parsed from the WCNF formatted stream afforded by wcnfReader, as
+// accepted by NewDimacs. Every clause starts with its weight, and
+// clauses whose weight is at least the top weight declared by the
+// header are hard. The header may be omitted, as in the format of
+// the MaxSAT evaluations since 2022, in which case hard clauses start
+// with 'h' rather than a weight and the number of variables is that
+// of the largest variable found in a clause.
+func NewWCNF(wcnfReader io.Reader) (*Dimacs, error) {
+	return parse(wcnfReader, true)
+}
 
-	variableSet := map[string]struct{}{}
-	numVariables := 0
-	numClauses := 0
-	var clauses []string
+func parse(r io.Reader, weighted bool) (*Dimacs, error) {
+	reader, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("error reading dimacs data: %w", err)
+	}
 
-	commentLine := regexp.MustCompile(`^c\s*.*`)
-	headerLine := regexp.MustCompile(`^p cnf\s+\d+\s+\d+\s*`)
-	clauseLine := regexp.MustCompile(`^(-?\d+\s+)+0`)
-	cleanInput := regexp.MustCompile(`\s\s+`)
+	p := parser{reader: reader, numVariables: -1, weighted: weighted}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
 
-	for {
-		line, err := reader.ReadString('\n')
+	// create variables
+	variables := make([]string, 0, p.numVariables)
+	for i := 1; i <= p.numVariables; i++ {
+		variables = append(variables, fmt.Sprint(i))
+	}
+	return &Dimacs{
+		variables:   variables,
+		clauses:     p.clauses,
+		lines:       p.lines,
+		weighted:    p.weighted,
+		soft:        p.soft,
+		incremental: p.incremental,
+		cubes:       p.cubes,
+	}, nil
+}
+
+// decompress returns a reader of the decompressed stream if the
+// stream starts with the magic number of a supported compression
+// format, or the stream itself otherwise.
+func decompress(reader *bufio.Reader) (*bufio.Reader, error) {
+	magic, err := reader.Peek(len(bzip2Magic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(reader)
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			return nil, fmt.Errorf("error reading dimacs data: %w", err)
+			return nil, err
 		}
-		line = strings.TrimSpace(line)
-		line = strings.Trim(line, "\n")
+		return bufio.NewReader(gz), nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bufio.NewReader(bzip2.NewReader(reader)), nil
+	}
+	return reader, nil
+}
 
-		// ignore comments
-		if commentLine.MatchString(line) {
-			continue
-		}
+type parser struct {
+	reader *bufio.Reader
 
-		// parse header
-		if headerLine.MatchString(line) {
-			line = cleanInput.ReplaceAllString(line, " ")
-			problem := strings.Split(line, " ")
-			if len(problem) != 4 {
-				return nil, fmt.Errorf("invalid statement: (%s). Valid format is p cnf <variables> <clauses>", line)
-			}
-			numVariables, err = strconv.Atoi(problem[2])
-			if err != nil {
-				return nil, fmt.Errorf("invalid number (%s) in statement (%s)", problem[2], line)
-			}
-			numClauses, err = strconv.Atoi(problem[3])
-			if err != nil {
-				return nil, fmt.Errorf("invalid number (%s) in statement (%s)", problem[3], line)
-			}
-			clauses = make([]string, 0, numClauses)
+	// line is the number of the line being parsed, starting at 1.
+	line int
+	// header is set once the header is found.
+	header bool
+	// numVariables and numClauses are declared by the header, and
+	// numVariables is negative until the header is found. If
+	// implicit is set, there is no header and numVariables is the
+	// largest variable found so far.
+	numVariables int
+	numClauses   int
+	implicit     bool
+	// weighted is set when parsing WCNF, where top is the weight
+	// from which clauses are hard, or 0 if all weighted clauses
+	// are soft.
+	weighted bool
+	top      int
+	// softWeight is the total weight of the soft clauses so far,
+	// which must fit in an int for their cost to be computed.
+	softWeight int
+	// incremental is set when parsing iCNF.
+	incremental bool
 
-			// parse next line
-			continue
-		}
+	clauses [][]int
+	lines   []int
+	soft    []WeightedClause
+	cubes   []Cube
+	// clause accumulates the literals of a clause until it is
+	// terminated by 0, possibly on a later line.
+	clause []int
+	// clauseLine and clauseColumn locate the first token of clause,
+	// which is its weight in WCNF.
+	clauseLine   int
+	clauseColumn int
+	// started is set once the first token of clause is read, and
+	// weight holds the weight of clause in WCNF, or 0 if it is hard.
+	// cube is set if clause is an assumption cube rather than a
+	// clause.
+	started bool
+	weight  int
+	cube    bool
+}
 
-		// collect clauses
-		if clauseLine.MatchString(line) {
-			if clauses == nil {
-				return nil, fmt.Errorf("invalid dimacs format: missing header 'p cnf <variable> <clauses>'")
-			}
-			line = cleanInput.ReplaceAllString(line, " ")
-			clause := strings.Split(line, " ")
-			if clause[len(clause)-1] != "0" {
-				return nil, fmt.Errorf("invalid clause (%s): does not end with 0", line)
-			}
-			clause = clause[:len(clause)-1]
-			if err := validateClause(clause, numVariables); err != nil {
-				return nil, fmt.Errorf("invalid clause (%s): %w", line, err)
-			}
+func (p *parser) errorf(column int, format string, args ...interface{}) error {
+	return &SyntaxError{Line: p.line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
 
-			// remember variables seen for final validation
-			// to ensure number of variables declared in header
-			// is the same as the number of variables used
-			for _, lit := range clause {
-				lit = strings.TrimPrefix(lit, "-")
-				variableSet[lit] = struct{}{}
-			}
-			clauses = append(clauses, strings.Join(clause, " "))
+func (p *parser) parse() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error reading dimacs data: %w", err)
+		}
+		if len(line) == 0 && err != nil {
+			break
+		}
+		p.line++
 
-			// parse next line
-			continue
+		done, perr := p.parseLine(line)
+		if perr != nil {
+			return perr
 		}
+		if done || err != nil {
+			break
+		}
+	}
 
-		// error out if the instruction is invalid
-		return nil, fmt.Errorf("invalid dimacs command: %s", line)
+	if p.numVariables < 0 {
+		return errors.New("invalid dimacs format: missing header 'p cnf <variables> <clauses>'")
+	}
+	if p.started {
+		return &SyntaxError{Line: p.clauseLine, Column: p.clauseColumn, Msg: "clause does not end with 0"}
 	}
+	// the number of clauses declared by the header is only a hint, and
+	// is often wrong in published benchmarks, but a header followed by
+	// no clause at all is most likely a truncated file
+	if !p.implicit && p.numClauses > 0 && len(p.clauses)+len(p.soft) == 0 {
+		return fmt.Errorf("invalid format: header declares %d clauses, found none", p.numClauses)
+	}
+	return nil
+}
 
-	if numVariables == 0 || numClauses == 0 || clauses == nil {
-		return nil, fmt.Errorf("invalid format: no variables or clauses found")
+// parseLine parses a single line, and reports whether it ends the
+// problem.
+func (p *parser) parseLine(line string) (bool, error) {
+	tokens := fields(line)
+	if len(tokens) == 0 {
+		return false, nil
 	}
 
-	if len(clauses) != numClauses {
-		return nil, fmt.Errorf("invalid format: number of clauses in header differ from the total number of clauses")
+	switch first := tokens[0]; {
+	case first.text[0] == 'c':
+		// ignore comments
+		return false, nil
+	case first.text[0] == '%':
+		return true, nil
+	case first.text == "p":
+		return false, p.parseHeader(tokens)
 	}
 
-	if len(variableSet) != numVariables {
-		return nil, fmt.Errorf("invalid format: number of variables in header differ from the total number of unique variables found in clauses")
+	// collect clauses
+	if p.numVariables < 0 {
+		if !p.weighted {
+			return false, p.errorf(tokens[0].column, "missing header 'p cnf <variables> <clauses>'")
+		}
+		p.numVariables, p.implicit = 0, true
 	}
+	for _, token := range tokens {
+		if !p.started {
+			p.started = true
+			p.clauseLine, p.clauseColumn = p.line, token.column
+			if p.weighted {
+				if err := p.parseWeight(token); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if p.incremental && token.text == "a" {
+				p.cube = true
+				continue
+			}
+		}
+		lit, err := strconv.Atoi(token.text)
+		if err != nil {
+			return false, p.errorf(token.column, "%s is not a number", token.text)
+		}
+		if lit == 0 {
+			p.endClause()
+			continue
+		}
+		if p.implicit {
+			if lit > p.numVariables {
+				p.numVariables = lit
+			} else if -lit > p.numVariables {
+				p.numVariables = -lit
+			}
+		} else if lit > p.numVariables || lit < -p.numVariables {
+			return false, p.errorf(token.column, "%d is not a valid variable", lit)
+		}
+		p.clause = append(p.clause, lit)
+	}
+	return false, nil
+}
 
-	// create variables
-	variables := make([]string, 0, numVariables)
-	for i := 1; i <= numVariables; i++ {
-		variables = append(variables, fmt.Sprint(i))
+// parseWeight parses the weight that starts a clause in WCNF.
+func (p *parser) parseWeight(token token) error {
+	if token.text == "h" {
+		p.weight = 0
+		return nil
 	}
-	return &Dimacs{
-		variables: variables,
-		clauses:   clauses,
-	}, nil
+	weight, err := strconv.Atoi(token.text)
+	if err != nil || weight < 1 {
+		return p.errorf(token.column, "invalid weight (%s)", token.text)
+	}
+	if p.top > 0 && weight >= p.top {
+		weight = 0
+	}
+	if weight > math.MaxInt-p.softWeight {
+		return p.errorf(token.column, "total weight of the soft clauses overflows")
+	}
+	p.weight = weight
+	p.softWeight += weight
+	return nil
 }
 
-func validateClause(clause []string, numVariables int) error {
-	for _, lit := range clause {
-		litInt, err := strconv.Atoi(lit)
-		if err != nil {
-			return fmt.Errorf("%s is not a number", lit)
+func (p *parser) endClause() {
+	if p.cube {
+		p.cubes = append(p.cubes, Cube{Clauses: len(p.clauses), Literals: p.clause, Line: p.clauseLine})
+	} else if p.weighted && p.weight > 0 {
+		p.soft = append(p.soft, WeightedClause{Weight: p.weight, Literals: p.clause})
+	} else {
+		p.clauses = append(p.clauses, p.clause)
+		p.lines = append(p.lines, p.clauseLine)
+	}
+	p.clause = nil
+	p.started = false
+	p.cube = false
+}
+
+func (p *parser) parseHeader(tokens []token) error {
+	if p.header {
+		return p.errorf(tokens[0].column, "duplicate header")
+	}
+	if p.implicit {
+		return p.errorf(tokens[0].column, "header must precede clauses")
+	}
+	p.header = true
+	switch {
+	case len(tokens) == 2 && tokens[1].text == "inccnf":
+		p.incremental = true
+		p.numVariables, p.implicit = 0, true
+		return nil
+	case len(tokens) == 4 && tokens[1].text == "cnf":
+	case (len(tokens) == 4 || len(tokens) == 5) && tokens[1].text == "wcnf":
+		p.weighted = true
+	default:
+		return p.errorf(tokens[0].column, "invalid header. Valid format is p cnf <variables> <clauses>")
+	}
+	var counts [3]int
+	for i, token := range tokens[2:] {
+		n, err := strconv.Atoi(token.text)
+		if err != nil || n < 0 {
+			return p.errorf(token.column, "invalid number (%s) in header", token.text)
 		}
-		if litInt == 0 {
-			return fmt.Errorf("0 is not a valid variable")
+		counts[i] = n
+	}
+	p.numVariables, p.numClauses, p.top = counts[0], counts[1], counts[2]
+	return nil
+}
+
+// token is a run of non-whitespace characters and its 1-based
+// column.
+type token struct {
+	text   string
+	column int
+}
+
+func fields(line string) []token {
+	var tokens []token
+	start := -1
+	for i := 0; i <= len(line); i++ {
+		if i < len(line) && !isSpace(line[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
 		}
-		if litInt > numVariables || -1*litInt < -1*numVariables {
-			return fmt.Errorf("%s is not a valid variable", lit)
+		if start >= 0 {
+			tokens = append(tokens, token{text: line[start:i], column: start + 1})
+			start = -1
 		}
 	}
-	return nil
+	return tokens
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
 }
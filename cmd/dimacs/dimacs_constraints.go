@@ -1,53 +1,319 @@
 package dimacs
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
 	"github.com/operator-framework/deppy/pkg/deppy/constraint"
 	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
 )
 
-func GenerateVariables(dimacs *Dimacs) ([]deppy.Variable, error) {
-	varMap := make(map[deppy.Identifier]*input.SimpleVariable, len(dimacs.variables))
-	variables := make([]deppy.Variable, 0, len(dimacs.variables))
+// GenerateProblem translates a CNF problem into a deppy.Problem with
+// one Variable per DIMACS variable and one global constraint.Clause
+// per clause, so that the selected Variables of any solution are
+// exactly the variables that are true in a model of the formula.
+//
+// Each soft clause of a weighted MaxSAT problem is relaxed by an
+// additional Variable, identified by RelaxationIdentifier, whose
+// selection satisfies the clause. Hard clauses carry the line of the
+// input they were read from as metadata, so that unsatisfiable cores
+// can be mapped back to the input with Core.
+func GenerateProblem(dimacs *Dimacs) (deppy.Problem, error) {
+	problem := deppy.Problem{
+		Variables:   make([]deppy.Variable, 0, len(dimacs.variables)+len(dimacs.soft)),
+		Constraints: make([]deppy.Constraint, 0, len(dimacs.clauses)+len(dimacs.soft)),
+	}
 
 	for _, id := range dimacs.variables {
-		variable := input.NewSimpleVariable(deppy.IdentifierFromString(id))
-		variables = append(variables, variable)
-		varMap[variable.Identifier()] = variable
+		problem.Variables = append(problem.Variables, input.NewSimpleVariable(deppy.IdentifierFromString(id)))
 	}
 
-	// create constraints out of the clauses
-	for _, clause := range dimacs.clauses {
-		terms := strings.Split(clause, " ")
-		if len(terms) == 0 {
-			continue
+	// create constraints out of the clauses, whose literals have
+	// been validated by NewDimacs
+	for i, clause := range dimacs.clauses {
+		problem.Constraints = append(problem.Constraints, dimacs.hardClause(i, clause))
+	}
+	for i, clause := range dimacs.soft {
+		relaxation := RelaxationIdentifier(i)
+		problem.Variables = append(problem.Variables, input.NewSimpleVariable(relaxation))
+		problem.Constraints = append(problem.Constraints, constraint.Clause(append(literals(clause.Literals), constraint.Positive(relaxation))...))
+	}
+
+	return problem, nil
+}
+
+// RelaxationIdentifier returns the Identifier of the Variable that
+// relaxes the soft clause at the given index of a weighted MaxSAT
+// problem.
+func RelaxationIdentifier(index int) deppy.Identifier {
+	return deppy.IdentifierFromString(fmt.Sprintf("r%d", index+1))
+}
+
+// LineLabel is the metadata label that holds the line of the input a
+// constraint was read from.
+const LineLabel = "line"
+
+// hardClause returns the constraint for the clause at the given index.
+func (d *Dimacs) hardClause(index int, clause []int) deppy.Constraint {
+	c := constraint.Clause(literals(clause)...)
+	if index >= len(d.lines) {
+		return c
+	}
+	return atLine(c, d.lines[index])
+}
+
+func atLine(c deppy.Constraint, line int) deppy.Constraint {
+	return constraint.WithMetadata(c, deppy.Metadata{
+		Labels: map[string]string{LineLabel: strconv.Itoa(line)},
+	})
+}
+
+func literals(clause []int) []constraint.Literal {
+	result := make([]constraint.Literal, len(clause))
+	for i, lit := range clause {
+		if lit < 0 {
+			result[i] = constraint.Negative(deppy.IdentifierFromString(fmt.Sprint(-lit)))
+		} else {
+			result[i] = constraint.Positive(deppy.IdentifierFromString(fmt.Sprint(lit)))
+		}
+	}
+	return result
+}
+
+// SolveOptimal solves a weighted MaxSAT problem, returning the
+// selected Variables of an assignment that satisfies every hard
+// clause and minimizes the total weight of the falsified soft
+// clauses, along with that weight.
+//
+// The soft clauses are split into strata, heaviest first, such that
+// every stratum but the last holds clauses of a single weight that
+// exceeds the total weight of the clauses of all lighter strata, so
+// that the strata can be optimized one after the other without losing
+// optimality. The optimum of each stratum is
+// found by a binary search over upper bounds on the weight of its
+// selected relaxation Variables, each enforced by a global
+// constraint.WeightedAtMost over weights divided by their greatest
+// common divisor, and is then kept as a bound while optimizing the
+// lighter strata. Splitting the weights this way keeps the weighted
+// constraints small for the hierarchical weights common in MaxSAT
+// benchmarks.
+func SolveOptimal(so *solver.Solver, dimacs *Dimacs) ([]deppy.Variable, int, error) {
+	problem, err := GenerateProblem(dimacs)
+	if err != nil {
+		return nil, 0, err
+	}
+	selection, err := so.SolveProblem(problem)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	constraints := problem.Constraints
+	for _, stratum := range strata(dimacs.soft) {
+		divisor := 0
+		for _, i := range stratum {
+			divisor = gcd(divisor, dimacs.soft[i].Weight)
+		}
+		weights := make(map[deppy.Identifier]int, len(stratum))
+		for _, i := range stratum {
+			weights[RelaxationIdentifier(i)] = dimacs.soft[i].Weight / divisor
+		}
+		cost := func(selection []deppy.Variable) int {
+			sum := 0
+			for _, variable := range selection {
+				sum += weights[variable.Identifier()]
+			}
+			return sum
 		}
-		first := terms[0]
 
-		if len(terms) == 1 {
-			// TODO: check constraints haven't already been added to the variable
-			variable := varMap[deppy.Identifier(strings.TrimPrefix(first, "-"))]
-			if strings.HasPrefix(first, "-") {
-				variable.AddConstraint(constraint.Not())
+		best := cost(selection)
+		for lo, hi := 0, best-1; lo <= hi; {
+			limit := lo + (hi-lo)/2
+			problem.Constraints = append(constraints[:len(constraints):len(constraints)], constraint.WeightedAtMost(limit, weights))
+			bounded, err := so.SolveProblem(problem)
+			var unsat deppy.NotSatisfiable
+			switch {
+			case errors.As(err, &unsat):
+				lo = limit + 1
+			case err != nil:
+				return nil, 0, err
+			default:
+				selection, best = bounded, cost(bounded)
+				hi = best - 1
+			}
+		}
+		constraints = append(constraints[:len(constraints):len(constraints)], constraint.WeightedAtMost(best, weights))
+	}
+	return selection, Cost(dimacs, selection), nil
+}
+
+// strata returns the indices of the soft clauses split into strata,
+// heaviest first. Every stratum but the last holds clauses of a single
+// weight, which exceeds the total weight of the clauses of the
+// following strata, so that any difference in cost within the stratum
+// outweighs the lighter strata. A weight that does not exceed the
+// total weight of the lighter clauses is merged with all of them into
+// the last stratum.
+func strata(soft []WeightedClause) [][]int {
+	indices := make([]int, len(soft))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return soft[indices[i]].Weight < soft[indices[j]].Weight
+	})
+
+	var result [][]int
+	var stratum []int
+	// lighter is the total weight of the clauses lighter than the
+	// current one, which fits in an int as checked by the parser.
+	lighter := 0
+	for k, i := range indices {
+		weight := soft[i].Weight
+		if k > 0 && weight != soft[indices[k-1]].Weight {
+			if weight > lighter {
+				result = append(result, stratum)
+				stratum = nil
 			} else {
-				// TODO: is this the right constraint here? (given that its an achoring constraint?)
-				variable.AddConstraint(constraint.Mandatory())
+				for _, lower := range result {
+					stratum = append(stratum, lower...)
+				}
+				result = nil
 			}
+		}
+		stratum = append(stratum, i)
+		lighter += weight
+	}
+	if len(stratum) > 0 {
+		result = append(result, stratum)
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Cost returns the total weight of the soft clauses of a weighted
+// MaxSAT problem that are falsified by the given selection.
+func Cost(dimacs *Dimacs, selection []deppy.Variable) int {
+	selected := selectedSet(selection)
+	cost := 0
+	for _, clause := range dimacs.soft {
+		satisfied := false
+		for _, lit := range clause.Literals {
+			_, ok := selected[deppy.IdentifierFromString(fmt.Sprint(abs(lit)))]
+			if ok == (lit > 0) {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			cost += clause.Weight
+		}
+	}
+	return cost
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// CoreClause is a clause of an unsatisfiable core, along with the line
+// of the input it was read from.
+type CoreClause struct {
+	Line     int   `json:"line"`
+	Literals []int `json:"literals"`
+}
+
+func (c CoreClause) String() string {
+	s := make([]string, 0, len(c.Literals)+1)
+	for _, lit := range c.Literals {
+		s = append(s, strconv.Itoa(lit))
+	}
+	return fmt.Sprintf("line %d: %s", c.Line, strings.Join(append(s, "0"), " "))
+}
+
+// Core maps the constraints of a NotSatisfiable error, returned for a
+// problem generated by GenerateProblem, back to the clauses of the
+// input, ordered by line. The assumptions of a cube of an incremental
+// problem appear as unit clauses on the line of the cube. Constraints
+// that were not read from the input are omitted.
+func Core(unsat deppy.NotSatisfiable) []CoreClause {
+	var core []CoreClause
+	for _, applied := range unsat {
+		line, err := strconv.Atoi(deppy.MetadataOf(applied.Constraint).Labels[LineLabel])
+		if err != nil {
 			continue
 		}
-		for i := 1; i < len(terms); i++ {
-			variable := varMap[deppy.Identifier(strings.TrimPrefix(first, "-"))]
-			second := terms[i]
-			negSubject := strings.HasPrefix(first, "-")
-			negOperand := strings.HasPrefix(second, "-")
+		clause, ok := unwrapClause(applied.Constraint)
+		if !ok {
+			continue
+		}
+		lits := make([]int, 0, len(clause.Literals))
+		for _, literal := range clause.Literals {
+			lit, err := strconv.Atoi(literal.ID.String())
+			if err != nil {
+				continue
+			}
+			if literal.Negated {
+				lit = -lit
+			}
+			lits = append(lits, lit)
+		}
+		core = append(core, CoreClause{Line: line, Literals: lits})
+	}
+	sort.Slice(core, func(i, j int) bool {
+		if core[i].Line != core[j].Line {
+			return core[i].Line < core[j].Line
+		}
+		// Assumptions of the same cube, in order of variable.
+		return len(core[i].Literals) > 0 && len(core[j].Literals) > 0 &&
+			abs(core[i].Literals[0]) < abs(core[j].Literals[0])
+	})
+	return core
+}
 
-			// TODO: this Or constraint is hacky as hell
-			variable.AddConstraint(constraint.Or(deppy.Identifier(strings.TrimPrefix(second, "-")), negSubject, negOperand))
-			first = second
+func unwrapClause(c deppy.Constraint) (*constraint.ClauseConstraint, bool) {
+	for {
+		if clause, ok := c.(*constraint.ClauseConstraint); ok {
+			return clause, true
 		}
+		u, ok := c.(interface{ Unwrap() deppy.Constraint })
+		if !ok {
+			return nil, false
+		}
+		c = u.Unwrap()
 	}
+}
 
-	return variables, nil
+// WriteCore writes the clauses of an unsatisfiable core to w as a CNF
+// problem in DIMACS format, over the variables of the given problem,
+// each preceded by a comment naming the line it was read from.
+func WriteCore(w io.Writer, dimacs *Dimacs, core []CoreClause) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p cnf %d %d\n", len(dimacs.variables), len(core))
+	for _, clause := range core {
+		fmt.Fprintf(bw, "c line %d\n", clause.Line)
+		for _, lit := range clause.Literals {
+			fmt.Fprintf(bw, "%d ", lit)
+		}
+		fmt.Fprintln(bw, "0")
+	}
+	return bw.Flush()
 }
@@ -2,9 +2,22 @@ package dimacs_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/go-air/gini"
+	"github.com/go-air/gini/z"
+
 	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -26,31 +39,535 @@ var _ = Describe("Dimacs", func() {
 	It("should fail if there are no clauses", func() {
 		problem := "p cnf 3 3\n"
 		_, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
-		Expect(err).To(HaveOccurred())
+		Expect(err).To(MatchError("invalid format: header declares 3 clauses, found none"))
+	})
+	It("should parse clauses spanning several lines", func() {
+		problem := "c a comment\np  cnf 3 2\n1 -2\n  3 0 -1\n0\n"
+		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Clauses()).To(Equal([][]int{{1, -2, 3}, {-1}}))
+	})
+	It("should accept variables that appear in no clause", func() {
+		problem := "p cnf 4 1\n1 -3 0\n"
+		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Variables()).To(Equal([]string{"1", "2", "3", "4"}))
+		Expect(d.Clauses()).To(Equal([][]int{{1, -3}}))
+	})
+	It("should accept a header that miscounts the clauses", func() {
+		for _, problem := range []string{"p cnf 2 3\n1 -2 0\n2 0\n", "p cnf 2 1\n1 -2 0\n2 0\n"} {
+			d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Clauses()).To(Equal([][]int{{1, -2}, {2}}))
+		}
+	})
+	It("should stop at a % line", func() {
+		problem := "p cnf 2 1\n 1 -2 0\n%\n0\n\n"
+		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Clauses()).To(Equal([][]int{{1, -2}}))
 	})
+	It("should read gzip compressed input", func() {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		_, err := w.Write([]byte("p cnf 2 1\n1 2 0\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		d, err := dimacs.NewDimacs(&compressed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Clauses()).To(Equal([][]int{{1, 2}}))
+	})
+	DescribeTable("should report the position of errors",
+		func(problem string, expected string) {
+			_, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+			Expect(err).To(MatchError(expected))
+			Expect(err).To(BeAssignableToTypeOf(&dimacs.SyntaxError{}))
+		},
+		Entry("invalid literal", "p cnf 2 1\n1 x 0\n", "line 2, column 3: x is not a number"),
+		Entry("unknown variable", "p cnf 2 1\n1\n  -3 0\n", "line 3, column 3: -3 is not a valid variable"),
+		Entry("unterminated clause", "p cnf 2 1\n1 2 0\n\n 2\n-1", "line 4, column 2: clause does not end with 0"),
+		Entry("clause before header", "1 2 0\np cnf 2 1\n", "line 1, column 1: missing header 'p cnf <variables> <clauses>'"),
+		Entry("invalid header", "p cnf 2\n1 2 0\n", "line 1, column 1: invalid header. Valid format is p cnf <variables> <clauses>"),
+		Entry("invalid count", "p cnf 2 -1\n", "line 1, column 9: invalid number (-1) in header"),
+		Entry("duplicate header", "p cnf 2 1\np cnf 2 1\n", "line 2, column 1: duplicate header"),
+	)
 	It("should parse valid dimacs", func() {
 		problem := "p cnf 3 1\n1 2 3 0\n"
 		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
 		Expect(err).ToNot(HaveOccurred())
 		Expect(d.Variables()).To(Equal([]string{"1", "2", "3"}))
-		Expect(d.Clauses()).To(Equal([]string{"1 2 3"}))
+		Expect(d.Clauses()).To(Equal([][]int{{1, 2, 3}}))
 	})
 })
 
 var _ = Describe("Dimacs Variable Source", func() {
-	It("should create variables for a dimacs problem", func() {
-		problem := "p cnf 3 1\n1 2 3 0\n"
+	It("should create a clause constraint for each clause", func() {
+		problem := "p cnf 3 2\n1 -2 3 0\n-1 0\n"
+		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		p, err := dimacs.GenerateProblem(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.Variables).To(HaveLen(3))
+
+		for i, id := range []deppy.Identifier{"1", "2", "3"} {
+			Expect(p.Variables[i].Identifier()).To(Equal(id))
+			Expect(p.Variables[i].Constraints()).To(BeEmpty())
+		}
+		atLine := func(line string) deppy.Metadata {
+			return deppy.Metadata{Labels: map[string]string{dimacs.LineLabel: line}}
+		}
+		Expect(p.Constraints).To(Equal([]deppy.Constraint{
+			constraint.WithMetadata(constraint.Clause(constraint.Positive("1"), constraint.Negative("2"), constraint.Positive("3")), atLine("2")),
+			constraint.WithMetadata(constraint.Clause(constraint.Negative("1")), atLine("3")),
+		}))
+	})
+	It("should map unsatisfiable cores back to clause lines", func() {
+		problem := "p cnf 3 4\nc unrelated\n2 0\n1\n-2 0\n\n-1 0\n1 3 0\n"
+		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		p, err := dimacs.GenerateProblem(d)
+		Expect(err).ToNot(HaveOccurred())
+		so, err := solver.New()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = so.SolveProblem(p)
+		var unsat deppy.NotSatisfiable
+		Expect(errors.As(err, &unsat)).To(BeTrue())
+
+		core := dimacs.Core(unsat)
+		Expect(core).To(Equal([]dimacs.CoreClause{
+			{Line: 3, Literals: []int{2}},
+			{Line: 4, Literals: []int{1, -2}},
+			{Line: 7, Literals: []int{-1}},
+		}))
+
+		var out bytes.Buffer
+		Expect(dimacs.WriteCore(&out, d, core)).To(Succeed())
+		Expect(out.String()).To(Equal("p cnf 3 3\nc line 3\n2 0\nc line 4\n1 -2 0\nc line 7\n-1 0\n"))
+	})
+})
+
+var _ = Describe("Dimacs Corpus", func() {
+	for _, expected := range []string{"sat", "unsat"} {
+		expected := expected
+		paths, err := filepath.Glob(filepath.Join("testdata", expected, "*.cnf*"))
+		if err != nil {
+			panic(err)
+		}
+		for _, path := range paths {
+			path := path
+			It(fmt.Sprintf("should agree with gini on %s", path), func() {
+				f, err := os.Open(path)
+				Expect(err).ToNot(HaveOccurred())
+				defer f.Close()
+				d, err := dimacs.NewDimacs(f)
+				Expect(err).ToNot(HaveOccurred())
+
+				// solve the clauses with gini directly
+				g := gini.New()
+				for _, clause := range d.Clauses() {
+					for _, lit := range clause {
+						g.Add(z.Dimacs2Lit(lit))
+					}
+					g.Add(z.LitNull)
+				}
+				Expect(g.Solve() == 1).To(Equal(expected == "sat"))
+
+				// solve the translated problem
+				problem, err := dimacs.GenerateProblem(d)
+				Expect(err).ToNot(HaveOccurred())
+				so, err := solver.New()
+				Expect(err).ToNot(HaveOccurred())
+				selection, err := so.SolveProblem(problem)
+				if expected == "unsat" {
+					Expect(err).To(BeAssignableToTypeOf(deppy.NotSatisfiable{}))
+					return
+				}
+				Expect(err).ToNot(HaveOccurred())
+
+				// the selection must be a model of every clause
+				selected := map[int]bool{}
+				for _, variable := range selection {
+					v, err := strconv.Atoi(variable.Identifier().String())
+					Expect(err).ToNot(HaveOccurred())
+					selected[v] = true
+				}
+				for _, clause := range d.Clauses() {
+					satisfied := false
+					for _, lit := range clause {
+						if lit > 0 && selected[lit] || lit < 0 && !selected[-lit] {
+							satisfied = true
+						}
+					}
+					Expect(satisfied).To(BeTrue(), "clause %v is not satisfied", clause)
+				}
+			})
+		}
+	}
+})
+
+var _ = Describe("Solve Command", func() {
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := dimacs.NewDimacsCommand()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	It("should list the value of every variable by default", func() {
+		out, err := run("testdata/sat/ternary.cnf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal("solution found:\n1 = true\n2 = false\n3 = false\n"))
+	})
+	It("should follow the SAT competition conventions", func() {
+		out, err := run("--output", "competition", "testdata/sat/ternary.cnf")
+		Expect(err).To(Equal(dimacs.ExitStatus(dimacs.ExitSatisfiable)))
+		Expect(out).To(Equal("s SATISFIABLE\nv 1 -2 -3 0\n"))
+
+		out, err = run("--output", "competition", "testdata/unsat/contradiction.cnf")
+		Expect(err).To(Equal(dimacs.ExitStatus(dimacs.ExitUnsatisfiable)))
+		Expect(out).To(HavePrefix("s UNSATISFIABLE\n"))
+	})
+	It("should split long model lines", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "wide.cnf")
+		Expect(os.WriteFile(path, []byte("p cnf 40 1\n1 0\n"), 0600)).To(Succeed())
+
+		out, err := run("-o", "competition", path)
+		Expect(err).To(Equal(dimacs.ExitStatus(dimacs.ExitSatisfiable)))
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		Expect(lines[0]).To(Equal("s SATISFIABLE"))
+		Expect(len(lines)).To(BeNumerically(">", 2))
+		var lits []string
+		for _, line := range lines[1:] {
+			Expect(len(line)).To(BeNumerically("<=", 80))
+			Expect(line).To(HavePrefix("v "))
+			lits = append(lits, strings.Fields(line)[1:]...)
+		}
+		Expect(lits).To(HaveLen(41))
+		Expect(lits[0]).To(Equal("1"))
+		Expect(lits[39]).To(Equal("-40"))
+		Expect(lits[40]).To(Equal("0"))
+	})
+	It("should write JSON", func() {
+		out, err := run("--output", "json", "testdata/sat/ternary.cnf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(MatchJSON(`{"status": "SATISFIABLE", "model": [1, -2, -3]}`))
+
+		out, err = run("--output", "json", "testdata/unsat/contradiction.cnf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(MatchJSON(`{"status": "UNSATISFIABLE", "core": [
+			{"line": 3, "literals": [1]},
+			{"line": 4, "literals": [-1]}
+		]}`))
+	})
+	It("should report unsatisfiable cores as clause lines", func() {
+		out, err := run("testdata/unsat/contradiction.cnf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal("no solution found, unsatisfiable core:\n  line 3: 1 0\n  line 4: -1 0\n"))
+
+		out, err = run("--output", "competition", "testdata/unsat/contradiction.cnf")
+		Expect(err).To(Equal(dimacs.ExitStatus(dimacs.ExitUnsatisfiable)))
+		Expect(out).To(Equal("s UNSATISFIABLE\nc core line 3: 1 0\nc core line 4: -1 0\n"))
+	})
+	It("should write unsatisfiable sub-problems", func() {
+		paths, err := filepath.Glob(filepath.Join("testdata", "unsat", "*.cnf*"))
+		Expect(err).ToNot(HaveOccurred())
+		for _, path := range paths {
+			core := filepath.Join(GinkgoT().TempDir(), "core.cnf")
+			_, err := run("--core", core, path)
+			Expect(err).ToNot(HaveOccurred())
+
+			f, err := os.Open(core)
+			Expect(err).ToNot(HaveOccurred())
+			d, err := dimacs.NewDimacs(f)
+			f.Close()
+			Expect(err).ToNot(HaveOccurred(), path)
+
+			g := gini.New()
+			for _, clause := range d.Clauses() {
+				for _, lit := range clause {
+					g.Add(z.Dimacs2Lit(lit))
+				}
+				g.Add(z.LitNull)
+			}
+			Expect(g.Solve()).To(Equal(-1), "core of %s is satisfiable", path)
+		}
+	})
+	It("should report the optimum of weighted problems", func() {
+		out, err := run("--output", "competition", "testdata/wcnf/zero-cost.wcnf")
+		Expect(err).To(Equal(dimacs.ExitStatus(dimacs.ExitOptimumFound)))
+		Expect(out).To(Equal("s OPTIMUM FOUND\no 0\nv -1 2 -3 0\n"))
+
+		out, err = run("--output", "json", "testdata/wcnf/classic-soft.wcnf")
+		Expect(err).ToNot(HaveOccurred())
+		var result struct {
+			Status string
+			Cost   int
+		}
+		Expect(json.Unmarshal([]byte(out), &result)).To(Succeed())
+		Expect(result.Status).To(Equal("OPTIMUM FOUND"))
+		Expect(result.Cost).To(Equal(6))
+
+		out, err = run("testdata/wcnf/evaluation.wcnf.gz")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(HavePrefix("optimal solution found with cost 3350:\n1 = "))
+	})
+	It("should reject unknown output formats", func() {
+		_, err := run("--output", "xml", "testdata/sat/ternary.cnf")
+		Expect(err).To(MatchError("invalid output format (xml): must be one of text, competition, json"))
+	})
+})
+
+var _ = Describe("WCNF", func() {
+	It("should separate hard clauses by the top weight", func() {
+		problem := "p wcnf 3 3 10\n10 1 2 0\n3 -1\n 0\n12 3 0\n"
 		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
 		Expect(err).ToNot(HaveOccurred())
-		variables, err := dimacs.GenerateVariables(d)
+		Expect(d.Weighted()).To(BeTrue())
+		Expect(d.Clauses()).To(Equal([][]int{{1, 2}, {3}}))
+		Expect(d.SoftClauses()).To(Equal([]dimacs.WeightedClause{{Weight: 3, Literals: []int{-1}}}))
+	})
+	It("should treat every clause as soft without a top weight", func() {
+		problem := "p wcnf 2 2\n1 1 2 0\n7 -2 0\n"
+		d, err := dimacs.NewWCNF(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Clauses()).To(BeEmpty())
+		Expect(d.SoftClauses()).To(Equal([]dimacs.WeightedClause{{Weight: 1, Literals: []int{1, 2}}, {Weight: 7, Literals: []int{-2}}}))
+	})
+	It("should read the headerless format", func() {
+		problem := "c comment\nh 1 -4 0\n2 4 0\n"
+		d, err := dimacs.NewWCNF(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Variables()).To(Equal([]string{"1", "2", "3", "4"}))
+		Expect(d.Clauses()).To(Equal([][]int{{1, -4}}))
+		Expect(d.SoftClauses()).To(Equal([]dimacs.WeightedClause{{Weight: 2, Literals: []int{4}}}))
+
+		_, err = dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).To(MatchError("line 2, column 1: missing header 'p cnf <variables> <clauses>'"))
+	})
+	DescribeTable("should report invalid weights",
+		func(problem string, expected string) {
+			_, err := dimacs.NewWCNF(bytes.NewReader([]byte(problem)))
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("zero", "p wcnf 1 1\n0 1 0\n", "line 2, column 1: invalid weight (0)"),
+		Entry("not a number", "x 1 0\n", "line 1, column 1: invalid weight (x)"),
+		Entry("unterminated", "h 1 0\n3\n", "line 2, column 1: clause does not end with 0"),
+		Entry("overflowing", "p wcnf 1 3\n4611686018427387904 1 0\n4611686018427387903 -1 0\n1 1 0\n", "line 4, column 1: total weight of the soft clauses overflows"),
+	)
+	It("should accept hard clauses beyond the total soft weight", func() {
+		_, err := dimacs.NewWCNF(bytes.NewReader([]byte("p wcnf 1 3 9223372036854775807\n9223372036854775807 1 0\n9223372036854775807 -1 1 0\n4611686018427387904 1 0\n")))
 		Expect(err).ToNot(HaveOccurred())
-		Expect(variables).To(HaveLen(3))
+	})
 
-		Expect(variables[0].Identifier()).To(Equal(deppy.Identifier("1")))
-		Expect(variables[0].Constraints()).To(HaveLen(1))
-		Expect(variables[1].Identifier()).To(Equal(deppy.Identifier("2")))
-		Expect(variables[1].Constraints()).To(HaveLen(1))
-		Expect(variables[2].Identifier()).To(Equal(deppy.Identifier("3")))
-		Expect(variables[2].Constraints()).To(BeEmpty())
+	paths, err := filepath.Glob(filepath.Join("testdata", "wcnf", "*.wcnf*"))
+	if err != nil {
+		panic(err)
+	}
+	for _, path := range paths {
+		path := path
+		It(fmt.Sprintf("should find the optimum of %s", path), func() {
+			f, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			d, err := dimacs.NewWCNF(f)
+			Expect(err).ToNot(HaveOccurred())
+
+			so, err := solver.New()
+			Expect(err).ToNot(HaveOccurred())
+			selection, cost, err := dimacs.SolveOptimal(so, d)
+
+			optimum, ok := bruteForceOptimum(d)
+			if !ok {
+				Expect(err).To(BeAssignableToTypeOf(deppy.NotSatisfiable{}))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cost).To(Equal(optimum))
+			Expect(dimacs.Cost(d, selection)).To(Equal(optimum))
+		})
+	}
+	It("should not optimize a weight apart from lighter weights it does not exceed", func() {
+		// 5 does not exceed 4 + 2 + 1, so that falsifying the clause
+		// of weight 4 rather than 5 is not worth falsifying the
+		// lighter clauses
+		d, err := dimacs.NewWCNF(bytes.NewReader([]byte("p wcnf 4 7 100\n100 -1 -2 0\n100 -2 -3 0\n100 -2 -4 0\n4 1 0\n5 2 0\n2 3 0\n1 4 0\n")))
+		Expect(err).ToNot(HaveOccurred())
+		so, err := solver.New()
+		Expect(err).ToNot(HaveOccurred())
+		selection, cost, err := dimacs.SolveOptimal(so, d)
+		Expect(err).ToNot(HaveOccurred())
+		optimum, ok := bruteForceOptimum(d)
+		Expect(ok).To(BeTrue())
+		Expect(optimum).To(Equal(5))
+		Expect(cost).To(Equal(5))
+		Expect(dimacs.Cost(d, selection)).To(Equal(5))
+	})
+})
+
+// bruteForceOptimum returns the least total weight of the soft clauses
+// falsified by an assignment that satisfies every hard clause, or
+// false if there is no such assignment.
+func bruteForceOptimum(d *dimacs.Dimacs) (int, bool) {
+	n := len(d.Variables())
+	holds := func(assignment int, clause []int) bool {
+		for _, lit := range clause {
+			v := lit
+			if v < 0 {
+				v = -v
+			}
+			if (assignment>>(v-1)&1 == 1) == (lit > 0) {
+				return true
+			}
+		}
+		return false
+	}
+	optimum, found := 0, false
+	for assignment := 0; assignment < 1<<n; assignment++ {
+		feasible := true
+		for _, clause := range d.Clauses() {
+			if !holds(assignment, clause) {
+				feasible = false
+				break
+			}
+		}
+		if !feasible {
+			continue
+		}
+		cost := 0
+		for _, clause := range d.SoftClauses() {
+			if !holds(assignment, clause.Literals) {
+				cost += clause.Weight
+			}
+		}
+		if !found || cost < optimum {
+			optimum, found = cost, true
+		}
+	}
+	return optimum, found
+}
+
+var _ = Describe("iCNF", func() {
+	It("should interleave clauses and assumption cubes", func() {
+		problem := "c comment\np inccnf\n1 2 0\na -1\n 0\n-2 3 0\na 0\n"
+		d, err := dimacs.NewDimacs(bytes.NewReader([]byte(problem)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Incremental()).To(BeTrue())
+		Expect(d.Variables()).To(Equal([]string{"1", "2", "3"}))
+		Expect(d.Clauses()).To(Equal([][]int{{1, 2}, {-2, 3}}))
+		Expect(d.ClauseLines()).To(Equal([]int{3, 6}))
+		Expect(d.Cubes()).To(Equal([]dimacs.Cube{{Clauses: 1, Literals: []int{-1}, Line: 4}, {Clauses: 2, Line: 7}}))
+	})
+	It("should only accept cubes in incremental problems", func() {
+		_, err := dimacs.NewDimacs(bytes.NewReader([]byte("p cnf 1 1\na 1 0\n")))
+		Expect(err).To(MatchError("line 2, column 1: a is not a number"))
+	})
+
+	It("should agree with gini on every cube", func() {
+		f, err := os.Open("testdata/icnf/random.icnf")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		d, err := dimacs.NewDimacs(f)
+		Expect(err).ToNot(HaveOccurred())
+
+		// solve each cube with gini directly
+		g := gini.New()
+		var expected []string
+		added := 0
+		for _, cube := range d.Cubes() {
+			for ; added < cube.Clauses; added++ {
+				for _, lit := range d.Clauses()[added] {
+					g.Add(z.Dimacs2Lit(lit))
+				}
+				g.Add(z.LitNull)
+			}
+			for _, lit := range cube.Literals {
+				g.Assume(z.Dimacs2Lit(lit))
+			}
+			if g.Solve() == 1 {
+				expected = append(expected, "SATISFIABLE")
+			} else {
+				expected = append(expected, "UNSATISFIABLE")
+			}
+		}
+		Expect(expected).To(ContainElement("SATISFIABLE"))
+		Expect(expected).To(ContainElement("UNSATISFIABLE"))
+
+		var out bytes.Buffer
+		cmd := dimacs.NewDimacsCommand()
+		cmd.SetArgs([]string{"--output", "json", "testdata/icnf/random.icnf"})
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Succeed())
+
+		decoder := json.NewDecoder(&out)
+		for i, status := range expected {
+			var result struct {
+				Cube   int
+				Status string
+				Model  []int
+			}
+			Expect(decoder.Decode(&result)).To(Succeed())
+			Expect(result.Cube).To(Equal(i + 1))
+			Expect(result.Status).To(Equal(status))
+			if status == "UNSATISFIABLE" {
+				continue
+			}
+
+			// the model must satisfy the cube and the clauses preceding it
+			cube := d.Cubes()[i]
+			holds := func(lit int) bool {
+				v := lit
+				if v < 0 {
+					v = -v
+				}
+				return result.Model[v-1] == lit
+			}
+			for _, lit := range cube.Literals {
+				Expect(holds(lit)).To(BeTrue(), "cube %d: assumption %d does not hold", i+1, lit)
+			}
+			for _, clause := range d.Clauses()[:cube.Clauses] {
+				satisfied := false
+				for _, lit := range clause {
+					satisfied = satisfied || holds(lit)
+				}
+				Expect(satisfied).To(BeTrue(), "cube %d: clause %v is not satisfied", i+1, clause)
+			}
+		}
+		Expect(decoder.More()).To(BeFalse())
+	})
+
+	It("should report every cube", func() {
+		var out bytes.Buffer
+		cmd := dimacs.NewDimacsCommand()
+		cmd.SetArgs([]string{"--output", "competition", "testdata/icnf/small.icnf"})
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Equal(dimacs.ExitStatus(dimacs.ExitUnsatisfiable)))
+		Expect(out.String()).To(Equal("s SATISFIABLE\nv -1 2 -3 0\ns SATISFIABLE\nv -1 2 3 0\ns UNSATISFIABLE\n" +
+			"c core line 2: 1 2 0\nc core line 4: -2 3 0\nc core line 6: -1 0\nc core line 6: -3 0\n"))
+	})
+	It("should report the core of every unsatisfiable cube", func() {
+		var out bytes.Buffer
+		cmd := dimacs.NewDimacsCommand()
+		cmd.SetArgs([]string{"--output", "competition", "testdata/icnf/cores.icnf"})
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Equal(dimacs.ExitStatus(dimacs.ExitUnsatisfiable)))
+		Expect(out.String()).To(Equal("s UNSATISFIABLE\n" +
+			"c core line 3: 1 2 0\nc core line 4: -1 0\nc core line 4: -2 0\n" +
+			"s SATISFIABLE\nv -1 2 3 0\n" +
+			"s SATISFIABLE\nv -1 2 3 0\n" +
+			"s UNSATISFIABLE\n" +
+			"c core line 7: 3 0\nc core line 9: -3 0\n" +
+			"s UNSATISFIABLE\n" +
+			"c core line 3: 1 2 0\nc core line 10: -1 0\nc core line 10: -2 0\n"))
+	})
+	It("should not blame a cube for the conflicts of a later one", func() {
+		var out bytes.Buffer
+		cmd := dimacs.NewDimacsCommand()
+		cmd.SetArgs([]string{"--output", "competition", "testdata/icnf/shared-literal.icnf"})
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Equal(dimacs.ExitStatus(dimacs.ExitUnsatisfiable)))
+		Expect(out.String()).To(Equal("s SATISFIABLE\nv 1 0\ns UNSATISFIABLE\nc core line 3: 1 0\nc core line 5: -1 0\n"))
 	})
 })
@@ -0,0 +1,36 @@
+package encode
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+)
+
+func NewEncodeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "encode <path>",
+		Short: "Encodes a problem given in YAML or JSON as a sat problem in dimacs format",
+		Long: `Encodes a problem given in YAML or JSON, as accepted by the resolve command, as
+the sat problem built by the solver, in dimacs format. The formula is satisfiable
+if and only if the hard constraints of the problem can be satisfied together.
+
+Comment lines map dimacs variables to the variables of the problem, and the
+literal encoding each constraint to a description of the constraint:
+c variable 2 a
+c constraint 2 a is mandatory
+c soft constraint 3 b is requested with priority 1
+
+The literal of each hard constraint is asserted by a unit clause, while soft
+constraints are not asserted. Other dimacs variables are internal to the encoding.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problem, err := input.LoadProblemFile(args[0])
+			if err != nil {
+				return err
+			}
+			return solver.WriteDIMACS(cmd.OutOrStdout(), problem)
+		},
+	}
+}
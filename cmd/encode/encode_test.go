@@ -0,0 +1,48 @@
+package encode_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/cmd/dimacs"
+	"github.com/operator-framework/deppy/cmd/encode"
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+)
+
+func TestEncode(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Encode Suite")
+}
+
+var _ = Describe("Encode Command", func() {
+	run := func(path string) *dimacs.Dimacs {
+		var out bytes.Buffer
+		cmd := encode.NewEncodeCommand()
+		cmd.SetArgs([]string{path})
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("c variable 2 a\n"))
+		d, err := dimacs.NewDimacs(&out)
+		Expect(err).ToNot(HaveOccurred())
+		return d
+	}
+	solve := func(d *dimacs.Dimacs) error {
+		problem, err := dimacs.GenerateProblem(d)
+		Expect(err).ToNot(HaveOccurred())
+		so, err := solver.New()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = so.SolveProblem(problem)
+		return err
+	}
+
+	It("should encode a satisfiable problem as a satisfiable formula", func() {
+		Expect(solve(run("testdata/sat.yaml"))).To(Succeed())
+	})
+	It("should encode an unsatisfiable problem as an unsatisfiable formula", func() {
+		Expect(solve(run("testdata/unsat.json"))).To(BeAssignableToTypeOf(deppy.NotSatisfiable{}))
+	})
+})
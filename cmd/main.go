@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -10,6 +11,12 @@ import (
 func main() {
 	rootCmd := root.NewRootCmd()
 	if err := rootCmd.Execute(); err != nil {
+		// commands may request a specific exit code, having
+		// already reported their outcome
+		var coded interface{ ExitCode() int }
+		if errors.As(err, &coded) {
+			os.Exit(coded.ExitCode())
+		}
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,86 @@
+package resolve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+)
+
+func NewResolveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resolve <path>",
+		Short: "Resolves a problem given in YAML or JSON",
+		Long: `Resolves a problem given in YAML or JSON and prints the selected variables,
+or the constraints that cannot be satisfied together. For instance:
+
+variables:
+  - id: a
+    constraints:
+      - type: mandatory
+      - type: dependency
+        dependencyIDs: [b, c]
+  - id: b
+  - id: c
+    constraints:
+      - type: conflict
+        conflictingID: b
+constraints:
+  - type: atMost
+    ids: [b, c]
+    n: 1
+
+See pkg/deppy/input/problem.schema.json for the full format.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problem, err := input.LoadProblemFile(args[0])
+			if err != nil {
+				return err
+			}
+			return Resolve(cmd.OutOrStdout(), problem)
+		},
+	}
+}
+
+// Resolve solves the given problem and writes the outcome to w. A
+// problem that has no solution is not an error: the constraints that
+// cannot be satisfied together are written to w instead.
+func Resolve(w io.Writer, problem deppy.Problem) error {
+	so, err := solver.New()
+	if err != nil {
+		return err
+	}
+
+	result, err := so.Resolve(problem)
+	var unsat deppy.NotSatisfiable
+	if errors.As(err, &unsat) {
+		fmt.Fprintln(w, "no solution found, the following constraints cannot be satisfied together:")
+		writeConstraints(w, unsat)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "solution found:")
+	for _, variable := range result.Selection {
+		fmt.Fprintf(w, "  %s\n", variable.Identifier())
+	}
+	for _, dropped := range result.Dropped {
+		fmt.Fprintf(w, "dropped %s, which conflicts with:\n", dropped.Constraint)
+		writeConstraints(w, dropped.Conflicts)
+	}
+	return nil
+}
+
+func writeConstraints(w io.Writer, constraints []deppy.AppliedConstraint) {
+	for _, c := range constraints {
+		fmt.Fprintf(w, "  - %s\n", c)
+	}
+}
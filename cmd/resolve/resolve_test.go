@@ -0,0 +1,97 @@
+package resolve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/cmd/resolve"
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+func TestResolve(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Resolve Suite")
+}
+
+var _ = Describe("Problem files", func() {
+	It("should load YAML problems", func() {
+		problem, err := input.LoadProblemFile("testdata/sat.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problem.Variables).To(HaveLen(3))
+		Expect(problem.Variables[0].Identifier()).To(Equal(deppy.Identifier("a")))
+		Expect(problem.Variables[0].Constraints()).To(HaveLen(2))
+		Expect(problem.Constraints).To(HaveLen(1))
+	})
+	It("should load JSON problems", func() {
+		problem, err := input.LoadProblemFile("testdata/unsat.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problem.Variables).To(HaveLen(2))
+		Expect(problem.Constraints).To(BeEmpty())
+	})
+	It("should reject invalid problems", func() {
+		_, err := input.LoadProblem(strings.NewReader(""))
+		Expect(err).To(MatchError("invalid problem: empty document"))
+		_, err = input.LoadProblem(strings.NewReader("variables:\n  - constraints: []\n"))
+		Expect(err).To(MatchError("invalid problem: variable 0 has no id"))
+		_, err = input.LoadProblem(strings.NewReader("variables:\n  - id: a\n  - id: a\n"))
+		Expect(err).To(MatchError(`invalid problem: duplicate variable "a"`))
+		_, err = input.LoadProblem(strings.NewReader(`{"variables": [], "constraint": []}`))
+		Expect(err).To(MatchError(ContainSubstring(`unknown field "constraint"`)))
+		_, err = input.LoadProblem(strings.NewReader("variables:\n  - id: a\n    constraints:\n      - type: mandatry\n"))
+		Expect(err).To(MatchError(ContainSubstring(`unknown constraint type "mandatry"`)))
+	})
+	It("should reject unknown fields at every level", func() {
+		_, err := input.LoadProblem(strings.NewReader("variables:\n  - id: a\n  - id: b\nconstraints:\n  - type: atMost\n    id: [a, b]\n    n: 1\n"))
+		Expect(err).To(MatchError(`invalid problem: invalid constraint of type "atMost": json: unknown field "id"`))
+		_, err = input.LoadProblem(strings.NewReader("variables:\n  - id: a\n    constrains:\n      - type: mandatory\n"))
+		Expect(err).To(MatchError(`invalid problem: json: unknown field "constrains"`))
+		_, err = input.LoadProblem(strings.NewReader(`{"variables": [{"ID": "a"}]}`))
+		Expect(err).To(MatchError(`invalid problem: json: unknown field "ID"`))
+		_, err = input.LoadProblem(strings.NewReader(`{"Variables": [{"id": "a"}]}`))
+		Expect(err).To(MatchError(`invalid problem: json: unknown field "Variables"`))
+		_, err = input.LoadProblem(strings.NewReader(`{"variables": [{"id": "a", "constraints": [{"type": "dependency", "dependencyIds": ["b"]}]}]}`))
+		Expect(err).To(MatchError(`invalid problem: invalid constraint of type "dependency": json: unknown field "dependencyIds"`))
+	})
+	It("should ship a schema covering every constraint type", func() {
+		var schema struct {
+			Defs map[string]json.RawMessage `json:"$defs"`
+		}
+		Expect(json.Unmarshal(input.ProblemSchema, &schema)).To(Succeed())
+		for _, name := range []string{"mandatory", "optional", "prohibited", "dependency", "recommends", "conflict", "atMost", "atLeast", "between", "weightedAtMost", "weightedAtLeast", "implies", "excludes", "mutuallyExclusive", "iff", "or", "clause", "expr", "annotated", "userFriendly"} {
+			Expect(schema.Defs).To(HaveKey(name))
+		}
+	})
+})
+
+var _ = Describe("Resolve", func() {
+	It("should print the selection and dropped constraints", func() {
+		problem, err := input.LoadProblemFile("testdata/sat.yaml")
+		Expect(err).ToNot(HaveOccurred())
+		var out bytes.Buffer
+		Expect(resolve.Resolve(&out, problem)).To(Succeed())
+		Expect(out.String()).To(Equal(`solution found:
+  a
+  c
+dropped b is requested with priority 1, which conflicts with:
+  - c is requested with priority 2
+  - b is requested with priority 1
+  - at most 1 of b, c may be selected
+`))
+	})
+	It("should print the constraints that cannot be satisfied", func() {
+		problem, err := input.LoadProblemFile("testdata/unsat.json")
+		Expect(err).ToNot(HaveOccurred())
+		var out bytes.Buffer
+		Expect(resolve.Resolve(&out, problem)).To(Succeed())
+		Expect(out.String()).To(HavePrefix("no solution found, the following constraints cannot be satisfied together:\n"))
+		Expect(out.String()).To(ContainSubstring("  - a is mandatory\n"))
+		Expect(out.String()).To(ContainSubstring("  - a requires at least one of b\n"))
+		Expect(out.String()).To(ContainSubstring("  - b is ProhibitedConstraint (origin: policy; reason: b is deprecated)\n"))
+	})
+})
@@ -5,7 +5,10 @@ import (
 
 	"github.com/operator-framework/deppy/cmd/sudoku"
 
+	"github.com/operator-framework/deppy/cmd/color"
 	"github.com/operator-framework/deppy/cmd/dimacs"
+	"github.com/operator-framework/deppy/cmd/encode"
+	"github.com/operator-framework/deppy/cmd/resolve"
 )
 
 func NewRootCmd() *cobra.Command {
@@ -18,7 +21,10 @@ For more information visit https://github.com/operator-framework/deppy`,
 
 	// add sub-commands
 	rootCmd.AddCommand(dimacs.NewDimacsCommand())
+	rootCmd.AddCommand(encode.NewEncodeCommand())
 	rootCmd.AddCommand(sudoku.NewSudokuCommand())
+	rootCmd.AddCommand(color.NewColorCommand())
+	rootCmd.AddCommand(resolve.NewResolveCommand())
 
 	return rootCmd
 }
@@ -1,26 +1,136 @@
 package sudoku
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
-
 	"github.com/operator-framework/deppy/pkg/deppy/solver"
 )
 
 func NewSudokuCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "sudoku",
-		Short: "Returns a solved sudoku board",
+	var seed int64
+	cmd := &cobra.Command{
+		Use:   "sudoku [path]",
+		Short: "Solves a sudoku puzzle, or returns a solved sudoku board",
+		Long: `Solves the sudoku puzzle read from the given file, or from standard input if
+the path is '-', and reports whether its solution is unique. The puzzle is
+given either as a line of 81 characters or as a grid of 9 rows, where empty
+cells are written as '.' or '0', for instance:
+
+53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79
+
+Grid borders made of '|', '-' and '+' are ignored, as are lines starting with '#'.
+Boards of 4x4, 16x16 and 25x25 cells are read the same way, with the numbers
+from 10 to 25 written as the letters from A to P, or as decimal numbers
+separated by whitespace. Without a path, a random solved 9x9 board is returned.
+`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return solve()
+			if !cmd.Flags().Changed("seed") {
+				seed = time.Now().UnixNano()
+			}
+			rng := rand.New(rand.NewSource(seed))
+			if len(args) == 0 {
+				return solve(cmd.OutOrStdout(), nil, rng)
+			}
+
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("error opening puzzle (%s): %w", args[0], err)
+				}
+				defer f.Close()
+				r = f
+			}
+			givens, err := ParseBoard(r)
+			if err != nil {
+				return fmt.Errorf("error parsing puzzle (%s): %w", args[0], err)
+			}
+			return solve(cmd.OutOrStdout(), &givens, rng)
 		},
 	}
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed for the random choices of the solver, for reproducible output")
+	cmd.AddCommand(newGenerateCommand())
+	return cmd
+}
+
+func newGenerateCommand() *cobra.Command {
+	var (
+		seed            int64
+		size, clues     int
+		includeSolution bool
+	)
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generates a sudoku puzzle with a unique solution",
+		Long: `Generates a sudoku puzzle with a unique solution, by emptying the cells of a
+random solved board for as long as its solution remains unique, down to the
+given number of clues. Puzzles whose solution is unique may require more clues
+than asked for, in which case the puzzle found with the fewest clues is
+returned. Boards of 4x4, 9x9, 16x16 and 25x25 cells are supported, which makes
+the generated puzzles a scalable benchmark for the solver.
+
+The puzzle is written in the format read by the sudoku command, preceded by a
+comment that describes it.
+`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch size {
+			case 4, 9, 16, 25:
+				return nil
+			}
+			return fmt.Errorf("invalid size (%d): must be one of 4, 9, 16, 25", size)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("seed") {
+				seed = time.Now().UnixNano()
+			}
+			return generate(cmd.OutOrStdout(), size, clues, seed, includeSolution)
+		},
+	}
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed for the random choices of the generator, for reproducible output")
+	cmd.Flags().IntVar(&size, "size", 9, "number of rows and columns of the board, one of 4, 9, 16, 25")
+	cmd.Flags().IntVar(&clues, "clues", 0, "number of clues to aim for")
+	cmd.Flags().BoolVar(&includeSolution, "solution", false, "also write the solution of the puzzle")
+	return cmd
+}
+
+// generate writes a puzzle of the given size with a unique solution.
+func generate(w io.Writer, size int, clues int, seed int64, includeSolution bool) error {
+	so, err := solver.New()
+	if err != nil {
+		return err
+	}
+	box := 1
+	for box*box < size {
+		box++
+	}
+	puzzle, solution, err := Generate(so, box, clues, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "# %dx%d puzzle with %d clues, seed %d\n", size, size, puzzle.Clues(), seed)
+	fmt.Fprint(w, puzzle)
+	if includeSolution {
+		fmt.Fprintln(w, "# solution")
+		fmt.Fprint(w, solution)
+	}
+	return nil
 }
 
-func solve() error {
+// solve writes the solution of the given puzzle, and whether it is
+// unique, or a random solved board if there is no puzzle, in which
+// case uniqueness is not checked.
+func solve(w io.Writer, givens *Board, rng *rand.Rand) error {
 	// build solver
 	so, err := solver.New()
 	if err != nil {
@@ -28,39 +138,30 @@ func solve() error {
 	}
 
 	// get solution
-	vars, err := GenerateVariables()
+	var solution Board
+	var unique bool
+	if givens != nil {
+		solution, unique, err = Solve(so, *givens, rng)
+	} else {
+		solution, err = Complete(so, NewBoard(3), rng)
+	}
+	var unsat deppy.NotSatisfiable
+	if errors.As(err, &unsat) {
+		fmt.Fprintln(w, "no solution found")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	selection, err := so.Solve(vars)
-	if err != nil {
-		fmt.Println("no solution found")
+
+	fmt.Fprint(w, solution)
+	if givens == nil {
+		return nil
+	}
+	if unique {
+		fmt.Fprintln(w, "the solution is unique")
 	} else {
-		selected := map[deppy.Identifier]struct{}{}
-		for _, variable := range selection {
-			selected[variable.Identifier()] = struct{}{}
-		}
-		for row := 0; row < 9; row++ {
-			for col := 0; col < 9; col++ {
-				found := false
-				for n := 0; n < 9; n++ {
-					id := GetID(row, col, n)
-					if _, ok := selected[id]; ok {
-						fmt.Printf("%d", n+1)
-						found = true
-						break
-					}
-				}
-				if !found {
-					fmt.Printf(" ")
-				}
-				if col != 8 {
-					fmt.Printf(" ")
-				}
-			}
-			fmt.Printf("\n")
-		}
+		fmt.Fprintln(w, "the solution is not unique")
 	}
-
 	return nil
 }
@@ -1,106 +1,438 @@
 package sudoku
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
 	"github.com/operator-framework/deppy/pkg/deppy/constraint"
 	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
 )
 
-func GetID(row int, col int, num int) deppy.Identifier {
+// Board is a sudoku board of n² rows and columns, split into n² boxes
+// of n×n cells. It is indexed by row and column, and holds the number
+// in each cell, from 1 to n², or 0 for empty cells.
+type Board [][]int
+
+// NewBoard returns an empty board whose boxes have the given size,
+// for instance 3 for the classic 9x9 board.
+func NewBoard(box int) Board {
+	size := box * box
+	board := make(Board, size)
+	for row := range board {
+		board[row] = make([]int, size)
+	}
+	return board
+}
+
+// Size returns the number of rows and columns of the board.
+func (b Board) Size() int {
+	return len(b)
+}
+
+// Box returns the number of rows and columns of each box.
+func (b Board) Box() int {
+	box := 0
+	for box*box < len(b) {
+		box++
+	}
+	return box
+}
+
+// Clues returns the number of non-empty cells.
+func (b Board) Clues() int {
+	clues := 0
+	for _, row := range b {
+		for _, n := range row {
+			if n != 0 {
+				clues++
+			}
+		}
+	}
+	return clues
+}
+
+// Copy returns a copy of the board.
+func (b Board) Copy() Board {
+	board := make(Board, len(b))
+	for row := range b {
+		board[row] = append([]int(nil), b[row]...)
+	}
+	return board
+}
+
+// symbols are the characters of the numbers of a cell, as written by
+// String and read by ParseBoard.
+const symbols = ".123456789ABCDEFGHIJKLMNOP"
+
+// String formats the board one row per line, with empty cells shown
+// as '.' and the numbers from 10 to 25 as the letters from A to P.
+func (b Board) String() string {
+	var sb strings.Builder
+	for _, row := range b {
+		for col, n := range row {
+			if col > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteByte(symbols[n])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ParseBoard reads a puzzle, either as a line of characters or as a
+// grid with one row per line, for instance
+//
+//	53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79
+//
+// or
+//
+//	5 3 . | . 7 . | . . .
+//	6 . . | 1 9 5 | . . .
+//	------+-------+------
+//	...
+//
+// Empty cells are written as '.' or '0', and the numbers from 10 to
+// 25 either as the letters from A to P or, in rows whose cells are
+// separated by whitespace, as decimal numbers. Whitespace and the
+// '|', '-' and '+' characters of grid borders are ignored, as are
+// lines starting with '#'. The size of the board, from 4x4 to 25x25,
+// is that of the number of cells read.
+func ParseBoard(r io.Reader) (Board, error) {
+	var cells, lines []int
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		row, err := parseRow(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		cells = append(cells, row...)
+		for range row {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	box := 2
+	for box*box*box*box < len(cells) {
+		box++
+	}
+	if box > 5 || box*box*box*box != len(cells) {
+		return nil, fmt.Errorf("found %d cells, expected 16, 81, 256 or 625", len(cells))
+	}
+	board := NewBoard(box)
+	size := board.Size()
+	for i, n := range cells {
+		if n > size {
+			return nil, fmt.Errorf("line %d: %d is too large for a %dx%d board", lines[i], n, size, size)
+		}
+		board[i/size][i%size] = n
+	}
+	return board, nil
+}
+
+// parseRow returns the cells of a line. Cells are written as decimal
+// numbers if they are all separated by whitespace, and as single
+// characters otherwise.
+func parseRow(text string) ([]int, error) {
+	var fields []string
+	for _, field := range strings.Fields(text) {
+		if strings.Trim(field, "|-+") != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) > 1 {
+		var row []int
+		for _, field := range fields {
+			if field == "." {
+				row = append(row, 0)
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 {
+				row = nil
+				break
+			}
+			row = append(row, n)
+		}
+		if row != nil {
+			return row, nil
+		}
+	}
+
+	var row []int
+	for _, c := range text {
+		n := strings.IndexRune(symbols, unicode.ToUpper(c))
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '|' || c == '-' || c == '+':
+			continue
+		case c == '0':
+			n = 0
+		case n < 0:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+		row = append(row, n)
+	}
+	return row, nil
+}
+
+// GetID returns the Identifier of the variable that places the number
+// num+1 in the given cell of a board of the given size.
+func GetID(size int, row int, col int, num int) deppy.Identifier {
 	n := num
-	n += col * 9
-	n += row * 81
-	return deppy.Identifier(fmt.Sprintf("%03d", n))
+	n += col * size
+	n += row * size * size
+	width := len(strconv.Itoa(size*size*size - 1))
+	return deppy.Identifier(fmt.Sprintf("%0*d", width, n))
 }
 
-func GenerateVariables() ([]deppy.Variable, error) {
+// GenerateProblem returns the problem of completing the given board,
+// whose non-empty cells are Mandatory. Unless rng is nil, the
+// preferences between the numbers of each cell are shuffled with rng,
+// so that boards with several solutions are completed differently for
+// different seeds.
+func GenerateProblem(givens Board, rng *rand.Rand) (deppy.Problem, error) {
 	// adapted from: https://github.com/go-air/gini/blob/871d828a26852598db2b88f436549634ba9533ff/sudoku_test.go#L10
-	variables := make(map[deppy.Identifier]*input.SimpleVariable, 0)
-	inorder := make([]deppy.Variable, 0)
+	var problem deppy.Problem
+	size, box := givens.Size(), givens.Box()
 
 	// create variables for all number in all positions of the board
-	for row := 0; row < 9; row++ {
-		for col := 0; col < 9; col++ {
-			for n := 0; n < 9; n++ {
-				variable := input.NewSimpleVariable(GetID(row, col, n))
-				variables[variable.Identifier()] = variable
-				inorder = append(inorder, variable)
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			for n := 0; n < size; n++ {
+				var constraints []deppy.Constraint
+				if givens[row][col] == n+1 {
+					constraints = append(constraints, constraint.Mandatory())
+				}
+				problem.Variables = append(problem.Variables, input.NewSimpleVariable(GetID(size, row, col, n), constraints...))
 			}
 		}
 	}
 
-	// add a clause stating that every position on the board has a number
-	for row := 0; row < 9; row++ {
-		for col := 0; col < 9; col++ {
-			ids := make([]deppy.Identifier, 9)
-			for n := 0; n < 9; n++ {
-				ids[n] = GetID(row, col, n)
+	// every position on the board has exactly one number
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			ids := make([]deppy.Identifier, size)
+			for n := 0; n < size; n++ {
+				ids[n] = GetID(size, row, col, n)
 			}
 			// randomize order to create new sudoku boards every run
-			rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
-
-			// create clause that the particular position has a number
-			varID := deppy.Identifier(fmt.Sprintf("%d-%d has a number", row, col))
-			variable := input.NewSimpleVariable(varID, constraint.Mandatory(), constraint.Dependency(ids...))
-			variables[varID] = variable
-			inorder = append(inorder, variable)
+			if rng != nil {
+				rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+			}
+			problem.Constraints = append(problem.Constraints, constraint.Exactly(1, ids...))
 		}
 	}
 
-	// every row has unique numbers
-	for n := 0; n < 9; n++ {
-		for row := 0; row < 9; row++ {
-			for colA := 0; colA < 9; colA++ {
-				idA := GetID(row, colA, n)
-				variable := variables[idA]
-				for colB := colA + 1; colB < 9; colB++ {
-					variable.AddConstraint(constraint.Conflict(GetID(row, colB, n)))
-				}
+	// every row, column and box has unique numbers
+	for n := 0; n < size; n++ {
+		for i := 0; i < size; i++ {
+			row := make([]deppy.Identifier, size)
+			col := make([]deppy.Identifier, size)
+			boxIDs := make([]deppy.Identifier, size)
+			for j := 0; j < size; j++ {
+				row[j] = GetID(size, i, j, n)
+				col[j] = GetID(size, j, i, n)
+				// box i is rooted at (i/box*box, i%box*box)
+				boxIDs[j] = GetID(size, i/box*box+j/box, i%box*box+j%box, n)
 			}
+			problem.Constraints = append(problem.Constraints,
+				constraint.MutuallyExclusive(row...),
+				constraint.MutuallyExclusive(col...),
+				constraint.MutuallyExclusive(boxIDs...),
+			)
 		}
 	}
 
-	// every column has unique numbers
-	for n := 0; n < 9; n++ {
-		for col := 0; col < 9; col++ {
-			for rowA := 0; rowA < 9; rowA++ {
-				idA := GetID(rowA, col, n)
-				variable := variables[idA]
-				for rowB := rowA + 1; rowB < 9; rowB++ {
-					variable.AddConstraint(constraint.Conflict(GetID(rowB, col, n)))
-				}
+	return problem, nil
+}
+
+// Complete returns a solution of the given board, without deciding
+// whether it is unique. If the board has no solution, a
+// deppy.NotSatisfiable error is returned.
+func Complete(so *solver.Solver, givens Board, rng *rand.Rand) (Board, error) {
+	problem, err := GenerateProblem(givens, rng)
+	if err != nil {
+		return nil, err
+	}
+	return complete(so, problem, givens.Box())
+}
+
+func complete(so *solver.Solver, problem deppy.Problem, box int) (Board, error) {
+	selection, err := so.SolveProblem(problem)
+	if err != nil {
+		return nil, err
+	}
+	return boardOf(box, selection), nil
+}
+
+// Solve completes the given board, and reports whether its solution
+// is unique. Uniqueness is decided by solving the problem a second
+// time, with a clause that rules out the first solution. If the board
+// has no solution, a deppy.NotSatisfiable error is returned.
+func Solve(so *solver.Solver, givens Board, rng *rand.Rand) (Board, bool, error) {
+	problem, err := GenerateProblem(givens, rng)
+	if err != nil {
+		return nil, false, err
+	}
+	solution, err := complete(so, problem, givens.Box())
+	if err != nil {
+		return nil, false, err
+	}
+
+	problem.Constraints = append(problem.Constraints, blockingClause(givens, solution))
+	_, err = so.SolveProblem(problem)
+	var unsat deppy.NotSatisfiable
+	if errors.As(err, &unsat) {
+		return solution, true, nil
+	}
+	return solution, false, err
+}
+
+// blockingClause returns a clause that rules out the numbers of a
+// solution in the empty cells of the given board.
+func blockingClause(givens Board, solution Board) deppy.Constraint {
+	size := givens.Size()
+	var block []constraint.Literal
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			if givens[row][col] == 0 {
+				block = append(block, constraint.Negative(GetID(size, row, col, solution[row][col]-1)))
 			}
 		}
 	}
+	return constraint.Clause(block...)
+}
 
-	// function adding constraints stating that every box on the board
-	// rooted at x, y has unique numbers
-	var box = func(x, y int) {
-		// all offsets w.r.t. root x,y
-		offs := []struct{ x, y int }{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
-		// all numbers
-		for n := 0; n < 9; n++ {
-			for i, offA := range offs {
-				idA := GetID(x+offA.x, y+offA.y, n)
-				variable := variables[idA]
-				for j := i + 1; j < len(offs); j++ {
-					offB := offs[j]
-					idB := GetID(x+offB.x, y+offB.y, n)
-					variable.AddConstraint(constraint.Conflict(idB))
-				}
+// Generate returns a puzzle whose boxes have the given size, along
+// with its unique solution. Starting from a random solved board, the
+// cells are emptied in random order as long as the solution remains
+// unique, until only the given number of clues is left or no other
+// cell can be emptied, so the puzzle may have more clues than asked
+// for.
+//
+// Every removal is checked with the same incremental solver, which
+// is asked for a solution other than the first under the remaining
+// clues.
+func Generate(so *solver.Solver, box int, clues int, rng *rand.Rand) (Board, Board, error) {
+	puzzle := NewBoard(box)
+	size := puzzle.Size()
+	problem, err := GenerateProblem(puzzle, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	inc, err := so.Incremental(problem.Variables, problem.Constraints...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Any numbering of the first box can be completed into a
+	// solution, whose rows and columns are then shuffled.
+	for j, n := range rng.Perm(size) {
+		puzzle[j/box][j%box] = n + 1
+	}
+	selection, err := inc.Solve(clueAssumptions(puzzle)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	solution := shuffle(boardOf(box, selection), rng)
+
+	// Every subsequent solution must differ from the first one.
+	if err := inc.AddConstraints(blockingClause(NewBoard(box), solution)); err != nil {
+		return nil, nil, err
+	}
+	puzzle = solution.Copy()
+	for _, cell := range rng.Perm(size * size) {
+		if puzzle.Clues() <= clues {
+			break
+		}
+		row, col := cell/size, cell%size
+		puzzle[row][col] = 0
+		_, err := inc.Solve(clueAssumptions(puzzle)...)
+		var unsat deppy.NotSatisfiable
+		if errors.As(err, &unsat) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		// another solution exists without this clue
+		puzzle[row][col] = solution[row][col]
+	}
+	return puzzle, solution, nil
+}
+
+// shuffle returns a solved board whose rows and columns are randomly
+// permuted, both within each band of boxes and between bands, which
+// keeps it solved.
+func shuffle(board Board, rng *rand.Rand) Board {
+	box, size := board.Box(), board.Size()
+	permutation := func() []int {
+		perm := make([]int, 0, size)
+		for _, band := range rng.Perm(box) {
+			for _, i := range rng.Perm(box) {
+				perm = append(perm, band*box+i)
 			}
 		}
+		return perm
+	}
+	rows, cols := permutation(), permutation()
+	shuffled := NewBoard(box)
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			shuffled[row][col] = board[rows[row]][cols[col]]
+		}
 	}
+	return shuffled
+}
 
-	// every box has unique numbers
-	for x := 0; x < 9; x += 3 {
-		for y := 0; y < 9; y += 3 {
-			box(x, y)
+// clueAssumptions returns a unit clause for the number of each
+// non-empty cell of the board.
+func clueAssumptions(board Board) []deppy.Constraint {
+	size := board.Size()
+	var assumptions []deppy.Constraint
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			if n := board[row][col]; n != 0 {
+				assumptions = append(assumptions, constraint.Clause(constraint.Positive(GetID(size, row, col, n-1))))
+			}
 		}
 	}
+	return assumptions
+}
 
-	return inorder, nil
+// boardOf returns the board whose cells hold the numbers selected by
+// a solution.
+func boardOf(box int, selection []deppy.Variable) Board {
+	selected := map[deppy.Identifier]struct{}{}
+	for _, variable := range selection {
+		selected[variable.Identifier()] = struct{}{}
+	}
+	board := NewBoard(box)
+	size := board.Size()
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			for n := 0; n < size; n++ {
+				if _, ok := selected[GetID(size, row, col, n)]; ok {
+					board[row][col] = n + 1
+					break
+				}
+			}
+		}
+	}
+	return board
 }
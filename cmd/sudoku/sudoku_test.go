@@ -0,0 +1,213 @@
+package sudoku_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+
+	"github.com/operator-framework/deppy/cmd/sudoku"
+)
+
+func TestSudoku(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sudoku Suite")
+}
+
+const puzzle = "53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79"
+
+const solution = `5 3 4 6 7 8 9 1 2
+6 7 2 1 9 5 3 4 8
+1 9 8 3 4 2 5 6 7
+8 5 9 7 6 1 4 2 3
+4 2 6 8 5 3 7 9 1
+7 1 3 9 2 4 8 5 6
+9 6 1 5 3 7 2 8 4
+2 8 7 4 1 9 6 3 5
+3 4 5 2 8 6 1 7 9
+`
+
+func parse(s string) sudoku.Board {
+	board, err := sudoku.ParseBoard(strings.NewReader(s))
+	Expect(err).ToNot(HaveOccurred())
+	return board
+}
+
+var _ = Describe("Board", func() {
+	It("should parse a line of 81 characters", func() {
+		board := parse(puzzle + "\n")
+		Expect(board[0]).To(Equal([]int{5, 3, 0, 0, 7, 0, 0, 0, 0}))
+		Expect(board[8]).To(Equal([]int{0, 0, 0, 0, 8, 0, 0, 7, 9}))
+		Expect(board.String()).To(HavePrefix("5 3 . . 7 . . . .\n"))
+	})
+	It("should parse a grid", func() {
+		f, err := os.Open("testdata/grid.txt")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		board, err := sudoku.ParseBoard(f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(board).To(Equal(parse(puzzle)))
+		Expect(parse(strings.ReplaceAll(puzzle, ".", "0"))).To(Equal(board))
+	})
+	It("should reject malformed puzzles", func() {
+		_, err := sudoku.ParseBoard(strings.NewReader(puzzle[1:]))
+		Expect(err).To(MatchError("found 80 cells, expected 16, 81, 256 or 625"))
+		_, err = sudoku.ParseBoard(strings.NewReader(puzzle + "\n1\n"))
+		Expect(err).To(MatchError("found 82 cells, expected 16, 81, 256 or 625"))
+		_, err = sudoku.ParseBoard(strings.NewReader("x" + puzzle[1:]))
+		Expect(err).To(MatchError(`line 1: unexpected character 'x'`))
+		_, err = sudoku.ParseBoard(strings.NewReader("1 2 . .\n. . 3 4\n. . 17 .\n. . . .\n"))
+		Expect(err).To(MatchError("line 3: 17 is too large for a 4x4 board"))
+	})
+	It("should parse boards of other sizes", func() {
+		board := parse("12..\n..34\n....\n....\n")
+		Expect(board.Size()).To(Equal(4))
+		Expect(board.Box()).To(Equal(2))
+		Expect(board[1]).To(Equal([]int{0, 0, 3, 4}))
+
+		board = parse(strings.Repeat("1 . 16 . . . . . . . . . . . . 0\n", 16))
+		Expect(board.Size()).To(Equal(16))
+		Expect(board[0][2]).To(Equal(16))
+		Expect(board.String()).To(HavePrefix("1 . G . "))
+		Expect(parse(board.String())).To(Equal(board))
+		Expect(parse(strings.ToLower(board.String()))).To(Equal(board))
+	})
+})
+
+var _ = Describe("Solve", func() {
+	var so *solver.Solver
+	BeforeEach(func() {
+		var err error
+		so, err = solver.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should complete a puzzle with a unique solution", func() {
+		board, unique, err := sudoku.Solve(so, parse(puzzle), rand.New(rand.NewSource(1)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unique).To(BeTrue())
+		Expect(board.String()).To(Equal(solution))
+	})
+	It("should detect puzzles with several solutions", func() {
+		// 1 and 3 can be swapped in the corners of this rectangle
+		givens := parse(solution)
+		givens[3][5], givens[3][8], givens[4][5], givens[4][8] = 0, 0, 0, 0
+		board, unique, err := sudoku.Solve(so, givens, rand.New(rand.NewSource(1)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unique).To(BeFalse())
+		Expect(board[3][5]).To(Equal(board[4][8]))
+		Expect(board[3][5] + board[3][8]).To(Equal(4))
+	})
+	It("should report puzzles without a solution", func() {
+		givens := parse(puzzle)
+		givens[0][2] = 5
+		_, _, err := sudoku.Solve(so, givens, rand.New(rand.NewSource(1)))
+		var unsat deppy.NotSatisfiable
+		Expect(errors.As(err, &unsat)).To(BeTrue())
+	})
+	It("should complete an empty board without deciding uniqueness", func() {
+		board, err := sudoku.Complete(so, sudoku.NewBoard(3), rand.New(rand.NewSource(7)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(board.Clues()).To(Equal(81))
+		solved, unique, err := sudoku.Solve(so, board, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unique).To(BeTrue())
+		Expect(solved).To(Equal(board))
+	})
+	It("should be reproducible for a given seed", func() {
+		empty := sudoku.NewBoard(3)
+		first, unique, err := sudoku.Solve(so, empty, rand.New(rand.NewSource(7)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unique).To(BeFalse())
+		second, _, err := sudoku.Solve(so, empty, rand.New(rand.NewSource(7)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(Equal(first))
+	})
+})
+
+var _ = Describe("Generate", func() {
+	var so *solver.Solver
+	BeforeEach(func() {
+		var err error
+		so, err = solver.New()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	for _, box := range []int{2, 3} {
+		box := box
+		It("should generate puzzles with a unique solution", func() {
+			puzzle, solution, err := sudoku.Generate(so, box, 0, rand.New(rand.NewSource(3)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(puzzle.Size()).To(Equal(box * box))
+			Expect(puzzle.Clues()).To(BeNumerically("<", box*box*box*box))
+			for row := range puzzle {
+				for col, n := range puzzle[row] {
+					if n != 0 {
+						Expect(n).To(Equal(solution[row][col]))
+					}
+				}
+			}
+
+			board, unique, err := sudoku.Solve(so, puzzle, rand.New(rand.NewSource(1)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unique).To(BeTrue())
+			Expect(board).To(Equal(solution))
+		})
+	}
+	It("should stop at the given number of clues", func() {
+		puzzle, _, err := sudoku.Generate(so, 3, 60, rand.New(rand.NewSource(3)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(puzzle.Clues()).To(Equal(60))
+	})
+	It("should be reproducible for a given seed", func() {
+		first, _, err := sudoku.Generate(so, 3, 40, rand.New(rand.NewSource(5)))
+		Expect(err).ToNot(HaveOccurred())
+		second, _, err := sudoku.Generate(so, 3, 40, rand.New(rand.NewSource(5)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(Equal(first))
+	})
+})
+
+var _ = Describe("Sudoku Command", func() {
+	It("should solve a puzzle read from stdin", func() {
+		var out bytes.Buffer
+		cmd := sudoku.NewSudokuCommand()
+		cmd.SetArgs([]string{"--seed", "1", "-"})
+		cmd.SetIn(strings.NewReader(puzzle + "\n"))
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(out.String()).To(Equal(solution + "the solution is unique\n"))
+	})
+	It("should generate a puzzle that it can solve", func() {
+		var out bytes.Buffer
+		cmd := sudoku.NewSudokuCommand()
+		cmd.SetArgs([]string{"generate", "--seed", "2", "--size", "4"})
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(out.String()).To(HavePrefix("# 4x4 puzzle with "))
+
+		puzzle := out.String()
+		out.Reset()
+		cmd = sudoku.NewSudokuCommand()
+		cmd.SetArgs([]string{"-"})
+		cmd.SetIn(strings.NewReader(puzzle))
+		cmd.SetOut(&out)
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(out.String()).To(HaveSuffix("the solution is unique\n"))
+	})
+	It("should reject unsupported sizes", func() {
+		cmd := sudoku.NewSudokuCommand()
+		cmd.SetArgs([]string{"generate", "--size", "5"})
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		Expect(cmd.Execute()).To(MatchError("invalid size (5): must be one of 4, 9, 16, 25"))
+	})
+})
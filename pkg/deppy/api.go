@@ -1,7 +1,9 @@
 package deppy
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/go-air/gini/logic"
@@ -50,6 +52,18 @@ type Variable interface {
 	Constraints() []Constraint
 }
 
+// VariableSource provides Variables on demand, so that only the part
+// of a problem that is reachable from its initial Variables needs to
+// be materialized.
+type VariableSource interface {
+	// Initial returns the Variables from which resolution starts.
+	// Anchors are typically among them.
+	Initial() ([]Variable, error)
+	// Lookup returns the Variable with the given Identifier, or
+	// false if the source does not provide one.
+	Lookup(id Identifier) (Variable, bool, error)
+}
+
 // LitMapping performs translation between the input and output types of
 // Solve (Constraints, Variables, etc.) and the variables that
 // appear in the SAT formula.
@@ -67,15 +81,205 @@ type Constraint interface {
 	Anchor() bool
 }
 
+// ChoiceConstraint may be implemented by a Constraint whose
+// preferences are made up of several independent choices rather than
+// the single choice among the Identifiers returned by Order. The
+// solver guesses one candidate from each choice, in preference order.
+type ChoiceConstraint interface {
+	Constraint
+	Choices() [][]Identifier
+}
+
+// SoftConstraint may be implemented by a Constraint that the solver
+// should satisfy if possible, rather than fail the resolution when it
+// cannot. Soft Constraints with a higher priority are satisfied in
+// preference to those with a lower priority.
+type SoftConstraint interface {
+	Constraint
+	Priority() int
+}
+
+// PriorityOf returns the priority of the given Constraint, or false
+// if it is not a SoftConstraint. A Constraint that wraps another by
+// implementing Unwrap() Constraint is soft if the wrapped Constraint
+// is.
+func PriorityOf(c Constraint) (int, bool) {
+	for {
+		if sc, ok := c.(SoftConstraint); ok {
+			return sc.Priority(), true
+		}
+		u, ok := c.(interface{ Unwrap() Constraint })
+		if !ok {
+			return 0, false
+		}
+		c = u.Unwrap()
+	}
+}
+
+// ValidatingConstraint may be implemented by a Constraint whose fields
+// can hold values that it cannot translate, such as weights whose sum
+// overflows. The solver fails with the error returned by Validate
+// rather than apply such a Constraint.
+type ValidatingConstraint interface {
+	Constraint
+	Validate() error
+}
+
+// Validate returns the error reported by the given Constraint, or by
+// any Constraint it wraps, if it is a ValidatingConstraint.
+func Validate(c Constraint) error {
+	for {
+		if vc, ok := c.(ValidatingConstraint); ok {
+			if err := vc.Validate(); err != nil {
+				return err
+			}
+		}
+		u, ok := c.(interface{ Unwrap() Constraint })
+		if !ok {
+			return nil
+		}
+		c = u.Unwrap()
+	}
+}
+
+// Metadata describes where a Constraint comes from and why it exists,
+// so that conflicts can be traced back to their source.
+type Metadata struct {
+	// Origin names the source of the Constraint, such as a bundle
+	// manifest, a cluster policy or a user request.
+	Origin string `json:"origin,omitempty"`
+	// Reason explains why the Constraint exists.
+	Reason string `json:"reason,omitempty"`
+	// Labels hold arbitrary key-value pairs for tooling.
+	Labels map[string]string `json:"labels,omitempty"`
+	// DocumentationURL links to further documentation about the
+	// Constraint.
+	DocumentationURL string `json:"documentationURL,omitempty"`
+}
+
+// IsZero returns true if the Metadata carries no information.
+func (m Metadata) IsZero() bool {
+	return m.Origin == "" && m.Reason == "" && len(m.Labels) == 0 && m.DocumentationURL == ""
+}
+
+// String returns a human-readable summary of the Metadata.
+func (m Metadata) String() string {
+	var s []string
+	if m.Origin != "" {
+		s = append(s, "origin: "+m.Origin)
+	}
+	if m.Reason != "" {
+		s = append(s, "reason: "+m.Reason)
+	}
+	if len(m.Labels) > 0 {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels := make([]string, len(keys))
+		for i, k := range keys {
+			labels[i] = fmt.Sprintf("%s=%s", k, m.Labels[k])
+		}
+		s = append(s, "labels: "+strings.Join(labels, ","))
+	}
+	if m.DocumentationURL != "" {
+		s = append(s, "see "+m.DocumentationURL)
+	}
+	return strings.Join(s, "; ")
+}
+
+// MetadataConstraint may be implemented by a Constraint that carries
+// Metadata.
+type MetadataConstraint interface {
+	Constraint
+	Metadata() Metadata
+}
+
+// MetadataOf returns the Metadata carried by the given Constraint, or
+// the zero Metadata if it carries none. A Constraint that wraps
+// another by implementing Unwrap() Constraint carries the Metadata of
+// the wrapped Constraint, if any.
+func MetadataOf(c Constraint) Metadata {
+	for {
+		if mc, ok := c.(MetadataConstraint); ok {
+			return mc.Metadata()
+		}
+		u, ok := c.(interface{ Unwrap() Constraint })
+		if !ok {
+			return Metadata{}
+		}
+		c = u.Unwrap()
+	}
+}
+
+// Problem is the complete input to a solver: the Variables that may
+// appear in a solution, and global Constraints that apply to the
+// Problem as a whole rather than to any particular Variable. Global
+// Constraints are applied with an empty subject, so only Constraints
+// that do not depend on their subject, such as AtMost, AtLeast,
+// MutuallyExclusive or Clause, are valid global Constraints. Solvers
+// reject those that do, such as Mandatory, Dependency or Conflict.
+type Problem struct {
+	Variables   []Variable
+	Constraints []Constraint
+}
+
 // AppliedConstraint values compose a single Constraint with the
-// Variable it applies to.
+// Variable it applies to. Variable is nil for a global Constraint of
+// a Problem.
 type AppliedConstraint struct {
 	Variable   Variable
 	Constraint Constraint
 }
 
+// Subject returns the Identifier of the Variable the Constraint
+// applies to, or the empty Identifier for a global Constraint.
+func (a AppliedConstraint) Subject() Identifier {
+	if a.Variable == nil {
+		return ""
+	}
+	return a.Variable.Identifier()
+}
+
+// Metadata returns the Metadata carried by the applied Constraint.
+func (a AppliedConstraint) Metadata() Metadata {
+	return MetadataOf(a.Constraint)
+}
+
 // String implements fmt.Stringer and returns a human-readable message
-// representing the receiver.
+// representing the receiver, followed by the Metadata of the
+// Constraint, if any.
 func (a AppliedConstraint) String() string {
-	return a.Constraint.String(a.Variable.Identifier())
+	msg := a.message()
+	if md := a.Metadata(); !md.IsZero() {
+		return fmt.Sprintf("%s (%s)", msg, md)
+	}
+	return msg
+}
+
+// message returns the message of the Constraint. Global Constraints
+// are given the empty subject, which Constraints that do not depend
+// on their subject describe without reference to one.
+func (a AppliedConstraint) message() string {
+	return a.Constraint.String(a.Subject())
+}
+
+// MarshalJSON implements json.Marshaler, rendering the applied
+// Constraint as the Identifier of its Variable, if any, its message
+// and its Metadata.
+func (a AppliedConstraint) MarshalJSON() ([]byte, error) {
+	var md *Metadata
+	if m := a.Metadata(); !m.IsZero() {
+		md = &m
+	}
+	return json.Marshal(struct {
+		Variable   Identifier `json:"variable,omitempty"`
+		Constraint string     `json:"constraint"`
+		Metadata   *Metadata  `json:"metadata,omitempty"`
+	}{
+		Variable:   a.Subject(),
+		Constraint: a.message(),
+		Metadata:   md,
+	})
 }
@@ -1,14 +1,21 @@
 package constraint
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 
 	"github.com/go-air/gini/z"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint/expr"
 )
 
+var _ deppy.ChoiceConstraint = &UserFriendlyConstraint{}
+
 type UserFriendlyConstraintMessageFormatter func(constraint deppy.Constraint, subject deppy.Identifier) string
 
 type UserFriendlyConstraint struct {
@@ -27,6 +34,117 @@ func NewUserFriendlyConstraint(constraint deppy.Constraint, messageFormatter Use
 	}
 }
 
+func (constraint *UserFriendlyConstraint) Unwrap() deppy.Constraint {
+	return constraint.Constraint
+}
+
+// Choices preserves the choices of the wrapped Constraint, if it has
+// any.
+func (constraint *UserFriendlyConstraint) Choices() [][]deppy.Identifier {
+	if cc, ok := constraint.Constraint.(deppy.ChoiceConstraint); ok {
+		return cc.Choices()
+	}
+	return [][]deppy.Identifier{constraint.Order()}
+}
+
+// subjectPlaceholder stands for the subject in the message of an
+// encoded UserFriendlyConstraint.
+const subjectPlaceholder = "{subject}"
+
+// MarshalJSON encodes the message of the constraint as a template in
+// which the subject is written as "{subject}", since the formatter
+// itself cannot be encoded.
+func (constraint *UserFriendlyConstraint) MarshalJSON() ([]byte, error) {
+	const marker = "\x00subject\x00"
+	message := strings.ReplaceAll(constraint.messageFormatter(constraint.Constraint, marker), marker, subjectPlaceholder)
+	return json.Marshal(userFriendlyConstraintEncoding{
+		Constraint: Envelope{constraint.Constraint},
+		Message:    message,
+	})
+}
+
+func (constraint *UserFriendlyConstraint) UnmarshalJSON(data []byte) error {
+	var encoding userFriendlyConstraintEncoding
+	if err := UnmarshalStrict(data, &encoding); err != nil {
+		return err
+	}
+	if encoding.Constraint.Constraint == nil {
+		return errors.New("missing constraint")
+	}
+	constraint.Constraint = encoding.Constraint.Constraint
+	constraint.messageFormatter = func(_ deppy.Constraint, subject deppy.Identifier) string {
+		return strings.ReplaceAll(encoding.Message, subjectPlaceholder, string(subject))
+	}
+	return nil
+}
+
+type userFriendlyConstraintEncoding struct {
+	Constraint Envelope `json:"constraint"`
+	Message    string   `json:"message"`
+}
+
+var _ deppy.MetadataConstraint = &AnnotatedConstraint{}
+var _ deppy.ChoiceConstraint = &AnnotatedConstraint{}
+
+// AnnotatedConstraint attaches Metadata to a Constraint without
+// otherwise changing its behavior.
+type AnnotatedConstraint struct {
+	deppy.Constraint
+	metadata deppy.Metadata
+}
+
+func (constraint *AnnotatedConstraint) Metadata() deppy.Metadata {
+	return constraint.metadata
+}
+
+func (constraint *AnnotatedConstraint) Unwrap() deppy.Constraint {
+	return constraint.Constraint
+}
+
+// Choices preserves the choices of the annotated Constraint, if it
+// has any.
+func (constraint *AnnotatedConstraint) Choices() [][]deppy.Identifier {
+	if cc, ok := constraint.Constraint.(deppy.ChoiceConstraint); ok {
+		return cc.Choices()
+	}
+	return [][]deppy.Identifier{constraint.Order()}
+}
+
+func (constraint *AnnotatedConstraint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(annotatedConstraintEncoding{
+		Constraint: Envelope{constraint.Constraint},
+		Metadata:   constraint.metadata,
+	})
+}
+
+func (constraint *AnnotatedConstraint) UnmarshalJSON(data []byte) error {
+	var encoding annotatedConstraintEncoding
+	if err := UnmarshalStrict(data, &encoding); err != nil {
+		return err
+	}
+	if encoding.Constraint.Constraint == nil {
+		return errors.New("missing constraint")
+	}
+	constraint.Constraint = encoding.Constraint.Constraint
+	constraint.metadata = encoding.Metadata
+	return nil
+}
+
+type annotatedConstraintEncoding struct {
+	Constraint Envelope       `json:"constraint"`
+	Metadata   deppy.Metadata `json:"metadata"`
+}
+
+// WithMetadata returns a Constraint that behaves like the given
+// Constraint and carries the given Metadata, which is reported
+// alongside the Constraint in conflicts.
+func WithMetadata(constraint deppy.Constraint, metadata deppy.Metadata) *AnnotatedConstraint {
+	return &AnnotatedConstraint{
+		Constraint: constraint,
+		metadata:   metadata,
+	}
+}
+
 type MandatoryConstraint struct{}
 
 func (constraint *MandatoryConstraint) String(subject deppy.Identifier) string {
@@ -51,6 +169,43 @@ func Mandatory() deppy.Constraint {
 	return &MandatoryConstraint{}
 }
 
+var _ deppy.SoftConstraint = &OptionalConstraint{}
+
+type OptionalConstraint struct {
+	Level int `json:"priority"`
+}
+
+func (constraint *OptionalConstraint) String(subject deppy.Identifier) string {
+	return fmt.Sprintf("%s is requested with priority %d", subject, constraint.Level)
+}
+
+func (constraint *OptionalConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LitOf(subject)
+}
+
+func (constraint *OptionalConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *OptionalConstraint) Anchor() bool {
+	return true
+}
+
+func (constraint *OptionalConstraint) Priority() int {
+	return constraint.Level
+}
+
+// Optional returns a Constraint that requests a particular Variable
+// in solutions, like Mandatory, but that the solver drops rather than
+// fail the resolution if it cannot be satisfied. When not every
+// Optional Constraint can be satisfied, those with a higher priority
+// are satisfied first.
+func Optional(priority int) deppy.Constraint {
+	return &OptionalConstraint{
+		Level: priority,
+	}
+}
+
 type ProhibitedConstraint struct{}
 
 func (constraint *ProhibitedConstraint) String(subject deppy.Identifier) string {
@@ -82,7 +237,7 @@ func Not() deppy.Constraint {
 }
 
 type DependencyConstraint struct {
-	DependencyIDs []deppy.Identifier
+	DependencyIDs []deppy.Identifier `json:"dependencyIDs"`
 }
 
 func (constraint *DependencyConstraint) String(subject deppy.Identifier) string {
@@ -123,8 +278,55 @@ func Dependency(ids ...deppy.Identifier) deppy.Constraint {
 	}
 }
 
+var _ deppy.SoftConstraint = &RecommendsConstraint{}
+
+type RecommendsConstraint struct {
+	RecommendedIDs []deppy.Identifier `json:"recommendedIDs"`
+}
+
+func (constraint *RecommendsConstraint) String(subject deppy.Identifier) string {
+	s := make([]string, len(constraint.RecommendedIDs))
+	for i, each := range constraint.RecommendedIDs {
+		s[i] = string(each)
+	}
+	return fmt.Sprintf("%s recommends at least one of %s", subject, strings.Join(s, ", "))
+}
+
+func (constraint *RecommendsConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	m := lm.LitOf(subject).Not()
+	for _, each := range constraint.RecommendedIDs {
+		m = lm.LogicCircuit().Or(m, lm.LitOf(each))
+	}
+	return m
+}
+
+func (constraint *RecommendsConstraint) Order() []deppy.Identifier {
+	return constraint.RecommendedIDs
+}
+
+func (constraint *RecommendsConstraint) Anchor() bool {
+	return false
+}
+
+// Priority is lower than that of any other soft constraint, so that
+// recommendations are only considered once every Optional anchor has
+// been decided.
+func (constraint *RecommendsConstraint) Priority() int {
+	return math.MinInt
+}
+
+// Recommends returns a Constraint that behaves like Dependency, but
+// that the solver drops rather than fail the resolution if it cannot
+// be satisfied. Identifiers appearing earlier in the argument list
+// have higher preference than those appearing later.
+func Recommends(ids ...deppy.Identifier) deppy.Constraint {
+	return &RecommendsConstraint{
+		RecommendedIDs: ids,
+	}
+}
+
 type ConflictConstraint struct {
-	ConflictingID deppy.Identifier
+	ConflictingID deppy.Identifier `json:"conflictingID"`
 }
 
 func (constraint *ConflictConstraint) String(subject deppy.Identifier) string {
@@ -153,15 +355,22 @@ func Conflict(id deppy.Identifier) deppy.Constraint {
 }
 
 type AtMostConstraint struct {
-	IDs []deppy.Identifier
-	N   int
+	IDs []deppy.Identifier `json:"ids"`
+	N   int                `json:"n"`
 }
 
+// String describes the constraint without reference to a subject when
+// the subject is empty, as it is for a global constraint of a
+// deppy.Problem. So do the other constraints that do not depend on
+// their subject.
 func (constraint *AtMostConstraint) String(subject deppy.Identifier) string {
 	s := make([]string, len(constraint.IDs))
 	for i, each := range constraint.IDs {
 		s[i] = string(each)
 	}
+	if subject == "" {
+		return fmt.Sprintf("at most %d of %s may be selected", constraint.N, strings.Join(s, ", "))
+	}
 	return fmt.Sprintf("%s permits at most %d of %s", subject, constraint.N, strings.Join(s, ", "))
 }
 
@@ -191,10 +400,557 @@ func AtMost(n int, ids ...deppy.Identifier) deppy.Constraint {
 	}
 }
 
+type AtLeastConstraint struct {
+	IDs []deppy.Identifier `json:"ids"`
+	N   int                `json:"n"`
+}
+
+func (constraint *AtLeastConstraint) String(subject deppy.Identifier) string {
+	s := make([]string, len(constraint.IDs))
+	for i, each := range constraint.IDs {
+		s[i] = string(each)
+	}
+	if subject == "" {
+		return fmt.Sprintf("at least %d of %s must be selected", constraint.N, strings.Join(s, ", "))
+	}
+	return fmt.Sprintf("%s requires at least %d of %s", subject, constraint.N, strings.Join(s, ", "))
+}
+
+func (constraint *AtLeastConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.IDs))
+	for i, each := range constraint.IDs {
+		ms[i] = lm.LitOf(each)
+	}
+	return lm.LogicCircuit().CardSort(ms).Geq(constraint.N)
+}
+
+func (constraint *AtLeastConstraint) Order() []deppy.Identifier {
+	if constraint.N <= 0 {
+		return nil
+	}
+	return constraint.IDs
+}
+
+func (constraint *AtLeastConstraint) Anchor() bool {
+	return false
+}
+
+// AtLeast returns a Constraint that forbids solutions that contain
+// fewer than n of the Variables identified by the given Identifiers.
+// Identifiers appearing earlier in the argument list have higher
+// preference than those appearing later.
+func AtLeast(n int, ids ...deppy.Identifier) deppy.Constraint {
+	return &AtLeastConstraint{
+		IDs: ids,
+		N:   n,
+	}
+}
+
+type BetweenConstraint struct {
+	IDs []deppy.Identifier `json:"ids"`
+	Min int                `json:"min"`
+	Max int                `json:"max"`
+}
+
+func (constraint *BetweenConstraint) String(subject deppy.Identifier) string {
+	s := make([]string, len(constraint.IDs))
+	for i, each := range constraint.IDs {
+		s[i] = string(each)
+	}
+	if subject == "" {
+		if constraint.Min == constraint.Max {
+			return fmt.Sprintf("exactly %d of %s must be selected", constraint.Min, strings.Join(s, ", "))
+		}
+		return fmt.Sprintf("between %d and %d of %s must be selected", constraint.Min, constraint.Max, strings.Join(s, ", "))
+	}
+	if constraint.Min == constraint.Max {
+		return fmt.Sprintf("%s requires exactly %d of %s", subject, constraint.Min, strings.Join(s, ", "))
+	}
+	return fmt.Sprintf("%s requires between %d and %d of %s", subject, constraint.Min, constraint.Max, strings.Join(s, ", "))
+}
+
+func (constraint *BetweenConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.IDs))
+	for i, each := range constraint.IDs {
+		ms[i] = lm.LitOf(each)
+	}
+	cs := lm.LogicCircuit().CardSort(ms)
+	return lm.LogicCircuit().And(cs.Geq(constraint.Min), cs.Leq(constraint.Max))
+}
+
+func (constraint *BetweenConstraint) Order() []deppy.Identifier {
+	if constraint.Min <= 0 {
+		return nil
+	}
+	return constraint.IDs
+}
+
+func (constraint *BetweenConstraint) Anchor() bool {
+	return false
+}
+
+// Between returns a Constraint that forbids solutions that contain
+// fewer than lo or more than hi of the Variables identified by the
+// given Identifiers. Identifiers appearing earlier in the argument
+// list have higher preference than those appearing later.
+func Between(lo, hi int, ids ...deppy.Identifier) deppy.Constraint {
+	return &BetweenConstraint{
+		IDs: ids,
+		Min: lo,
+		Max: hi,
+	}
+}
+
+// Exactly returns a Constraint that forbids solutions that do not
+// contain exactly n of the Variables identified by the given
+// Identifiers. Identifiers appearing earlier in the argument list
+// have higher preference than those appearing later.
+func Exactly(n int, ids ...deppy.Identifier) deppy.Constraint {
+	return Between(n, n, ids...)
+}
+
+type WeightedAtMostConstraint struct {
+	Weights map[deppy.Identifier]int `json:"weights"`
+	Limit   int                      `json:"limit"`
+}
+
+func (constraint *WeightedAtMostConstraint) String(subject deppy.Identifier) string {
+	if subject == "" {
+		return fmt.Sprintf("a total weight of at most %d may be selected from %s", constraint.Limit, formatWeights(constraint.Weights))
+	}
+	return fmt.Sprintf("%s permits a total weight of at most %d from %s", subject, constraint.Limit, formatWeights(constraint.Weights))
+}
+
+func (constraint *WeightedAtMostConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	return weightedLeq(lm, constraint.Weights, constraint.Limit)
+}
+
+func (constraint *WeightedAtMostConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *WeightedAtMostConstraint) Anchor() bool {
+	return false
+}
+
+// Validate reports weights and limits whose magnitudes sum beyond the
+// range of an int.
+func (constraint *WeightedAtMostConstraint) Validate() error {
+	return validateWeights(constraint.Weights, constraint.Limit)
+}
+
+// WeightedAtMost returns a Constraint that forbids solutions in which
+// the weights of the selected Variables among those identified by the
+// keys of weights sum to more than limit.
+func WeightedAtMost(limit int, weights map[deppy.Identifier]int) deppy.Constraint {
+	return &WeightedAtMostConstraint{
+		Weights: weights,
+		Limit:   limit,
+	}
+}
+
+type WeightedAtLeastConstraint struct {
+	Weights map[deppy.Identifier]int `json:"weights"`
+	Limit   int                      `json:"limit"`
+}
+
+func (constraint *WeightedAtLeastConstraint) String(subject deppy.Identifier) string {
+	if subject == "" {
+		return fmt.Sprintf("a total weight of at least %d must be selected from %s", constraint.Limit, formatWeights(constraint.Weights))
+	}
+	return fmt.Sprintf("%s requires a total weight of at least %d from %s", subject, constraint.Limit, formatWeights(constraint.Weights))
+}
+
+func (constraint *WeightedAtLeastConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	return weightedLeq(lm, constraint.Weights, constraint.Limit-1).Not()
+}
+
+// Order prefers the Variables with positive weights, heaviest first,
+// so that the search needs as few of them as possible to reach the
+// limit.
+func (constraint *WeightedAtLeastConstraint) Order() []deppy.Identifier {
+	if constraint.Limit <= 0 {
+		return nil
+	}
+	var ids []deppy.Identifier
+	for _, id := range sortedIdentifiers(constraint.Weights) {
+		if constraint.Weights[id] > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return constraint.Weights[ids[i]] > constraint.Weights[ids[j]]
+	})
+	return ids
+}
+
+func (constraint *WeightedAtLeastConstraint) Anchor() bool {
+	return false
+}
+
+// Validate reports weights and limits whose magnitudes sum beyond the
+// range of an int.
+func (constraint *WeightedAtLeastConstraint) Validate() error {
+	return validateWeights(constraint.Weights, constraint.Limit)
+}
+
+// WeightedAtLeast returns a Constraint that forbids solutions in which
+// the weights of the selected Variables among those identified by the
+// keys of weights sum to less than limit.
+func WeightedAtLeast(limit int, weights map[deppy.Identifier]int) deppy.Constraint {
+	return &WeightedAtLeastConstraint{
+		Weights: weights,
+		Limit:   limit,
+	}
+}
+
+func sortedIdentifiers(weights map[deppy.Identifier]int) []deppy.Identifier {
+	ids := make([]deppy.Identifier, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func formatWeights(weights map[deppy.Identifier]int) string {
+	ids := sortedIdentifiers(weights)
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = fmt.Sprintf("%s (%d)", id, weights[id])
+	}
+	return strings.Join(s, ", ")
+}
+
+// validateWeights returns an error if the sum of the magnitudes of
+// the weights and of the limit does not fit in an int, which is the
+// range that weightedLeq computes in.
+func validateWeights(weights map[deppy.Identifier]int, limit int) error {
+	if limit < -math.MaxInt {
+		return fmt.Errorf("limit %d is out of range", limit)
+	}
+	total := abs(limit)
+	for _, id := range sortedIdentifiers(weights) {
+		w := weights[id]
+		if w < -math.MaxInt || abs(w) > math.MaxInt-total {
+			return fmt.Errorf("weights and limit overflow at %s (%d)", id, w)
+		}
+		total += abs(w)
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// weightedTerm is a literal with a positive weight.
+type weightedTerm struct {
+	m z.Lit
+	w int
+}
+
+// weightedInterval is a node of the decision diagram built by
+// weightedLeq for the terms from a given index on, along with the
+// interval of limits [lo, hi] for which that node is the answer.
+type weightedInterval struct {
+	lo, hi int
+	m      z.Lit
+}
+
+// weightedLeq returns a literal that is true exactly when the weights
+// of the selected Variables sum to at most limit, or false if the
+// weights and limit fail validateWeights.
+//
+// The sum is encoded as a reduced ordered decision diagram over the
+// terms, heaviest first. Every node is recorded along with the
+// interval of limits for which it holds (Abío et al., "A New Look at
+// BDDs for Pseudo-Boolean Constraints", 2012), so that every limit
+// that leads to an existing node finds it without further recursion.
+// The size of the diagram depends on how the weights combine rather
+// than on their magnitude: scaling every weight and the limit by the
+// same factor yields the same diagram. It is polynomial for
+// cardinality-like constraints, but can still grow exponentially with
+// the number of terms for some sets of unrelated weights.
+func weightedLeq(lm deppy.LitMapping, weights map[deppy.Identifier]int, limit int) z.Lit {
+	c := lm.LogicCircuit()
+	if validateWeights(weights, limit) != nil {
+		return c.F
+	}
+	var terms []weightedTerm
+	for _, id := range sortedIdentifiers(weights) {
+		m, w := lm.LitOf(id), weights[id]
+		switch {
+		case w > 0:
+			terms = append(terms, weightedTerm{m: m, w: w})
+		case w < 0:
+			// w·x = w + (-w)·¬x
+			terms = append(terms, weightedTerm{m: m.Not(), w: -w})
+			limit -= w
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return terms[i].w > terms[j].w
+	})
+
+	// rest[i] is the sum of the weights of terms[i:].
+	rest := make([]int, len(terms)+1)
+	for i := len(terms) - 1; i >= 0; i-- {
+		rest[i] = rest[i+1] + terms[i].w
+	}
+
+	// shift adds a weight to an interval bound, leaving the
+	// unbounded ends unbounded.
+	shift := func(bound, w int) int {
+		if bound == math.MinInt || bound == math.MaxInt {
+			return bound
+		}
+		return bound + w
+	}
+
+	// levels[i] holds the nodes for terms[i:], ordered by interval.
+	levels := make([][]weightedInterval, len(terms))
+	// leq returns a literal that is true exactly when the selected
+	// terms among terms[i:] sum to at most k, along with the
+	// interval of limits around k for which that literal is the
+	// answer.
+	var leq func(i, k int) weightedInterval
+	leq = func(i, k int) weightedInterval {
+		if k < 0 {
+			return weightedInterval{lo: math.MinInt, hi: -1, m: c.F}
+		}
+		if rest[i] <= k {
+			return weightedInterval{lo: rest[i], hi: math.MaxInt, m: c.T}
+		}
+		level := levels[i]
+		j := sort.Search(len(level), func(j int) bool { return level[j].hi >= k })
+		if j < len(level) && level[j].lo <= k {
+			return level[j]
+		}
+
+		t := terms[i]
+		// the terms are bounded by validateWeights, so k-t.w
+		// cannot overflow
+		selected, unselected := leq(i+1, k-t.w), leq(i+1, k)
+		node := weightedInterval{
+			lo: max(shift(selected.lo, t.w), unselected.lo),
+			hi: min(shift(selected.hi, t.w), unselected.hi),
+			m:  unselected.m,
+		}
+		if selected.m != unselected.m {
+			node.m = c.Choice(t.m, selected.m, unselected.m)
+		}
+		levels[i] = append(levels[i], weightedInterval{})
+		copy(levels[i][j+1:], levels[i][j:])
+		levels[i][j] = node
+		return node
+	}
+	return leq(0, limit).m
+}
+
+var _ deppy.ChoiceConstraint = &ImpliesConstraint{}
+
+type ImpliesConstraint struct {
+	ImpliedIDs []deppy.Identifier `json:"impliedIDs"`
+}
+
+func (constraint *ImpliesConstraint) String(subject deppy.Identifier) string {
+	s := make([]string, len(constraint.ImpliedIDs))
+	for i, each := range constraint.ImpliedIDs {
+		s[i] = string(each)
+	}
+	return fmt.Sprintf("%s requires all of %s", subject, strings.Join(s, ", "))
+}
+
+func (constraint *ImpliesConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.ImpliedIDs))
+	for i, each := range constraint.ImpliedIDs {
+		ms[i] = lm.LitOf(each)
+	}
+	return lm.LogicCircuit().Implies(lm.LitOf(subject), lm.LogicCircuit().Ands(ms...))
+}
+
+func (constraint *ImpliesConstraint) Order() []deppy.Identifier {
+	return constraint.ImpliedIDs
+}
+
+// Choices returns a separate choice for each implied Variable, since
+// all of them must be selected along with the subject.
+func (constraint *ImpliesConstraint) Choices() [][]deppy.Identifier {
+	choices := make([][]deppy.Identifier, len(constraint.ImpliedIDs))
+	for i := range constraint.ImpliedIDs {
+		choices[i] = constraint.ImpliedIDs[i : i+1]
+	}
+	return choices
+}
+
+func (constraint *ImpliesConstraint) Anchor() bool {
+	return false
+}
+
+// Implies returns a Constraint that will only permit solutions
+// containing a given Variable on the condition that every one of the
+// Variables identified by the given Identifiers also appears in the
+// solution.
+func Implies(ids ...deppy.Identifier) deppy.Constraint {
+	return &ImpliesConstraint{
+		ImpliedIDs: ids,
+	}
+}
+
+type ExcludesConstraint struct {
+	ExcludedIDs []deppy.Identifier `json:"excludedIDs"`
+}
+
+func (constraint *ExcludesConstraint) String(subject deppy.Identifier) string {
+	s := make([]string, len(constraint.ExcludedIDs))
+	for i, each := range constraint.ExcludedIDs {
+		s[i] = string(each)
+	}
+	return fmt.Sprintf("%s excludes all of %s", subject, strings.Join(s, ", "))
+}
+
+func (constraint *ExcludesConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.ExcludedIDs))
+	for i, each := range constraint.ExcludedIDs {
+		ms[i] = lm.LitOf(each).Not()
+	}
+	return lm.LogicCircuit().Implies(lm.LitOf(subject), lm.LogicCircuit().Ands(ms...))
+}
+
+func (constraint *ExcludesConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *ExcludesConstraint) Anchor() bool {
+	return false
+}
+
+// Excludes returns a Constraint that will only permit solutions
+// containing a given Variable on the condition that none of the
+// Variables identified by the given Identifiers appears in the
+// solution.
+func Excludes(ids ...deppy.Identifier) deppy.Constraint {
+	return &ExcludesConstraint{
+		ExcludedIDs: ids,
+	}
+}
+
+// ConflictsWithAny returns a Constraint that will only permit
+// solutions containing a given Variable on the condition that none of
+// the Variables identified by the given Identifiers appears in the
+// solution. Unlike a Conflict per Identifier, the whole set is
+// reported as a single conflict.
+func ConflictsWithAny(ids ...deppy.Identifier) deppy.Constraint {
+	return Excludes(ids...)
+}
+
+// RequiresNoneOf is an alias for ConflictsWithAny.
+func RequiresNoneOf(ids ...deppy.Identifier) deppy.Constraint {
+	return Excludes(ids...)
+}
+
+type MutuallyExclusiveConstraint struct {
+	IDs []deppy.Identifier `json:"ids"`
+}
+
+// String describes the group without reference to the subject, since
+// the constraint applies symmetrically to all of its members.
+func (constraint *MutuallyExclusiveConstraint) String(_ deppy.Identifier) string {
+	s := make([]string, len(constraint.IDs))
+	for i, each := range constraint.IDs {
+		s[i] = string(each)
+	}
+	return fmt.Sprintf("at most one of %s may be selected", strings.Join(s, ", "))
+}
+
+func (constraint *MutuallyExclusiveConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.IDs))
+	for i, each := range constraint.IDs {
+		ms[i] = lm.LitOf(each)
+	}
+	return lm.LogicCircuit().CardSort(ms).Leq(1)
+}
+
+func (constraint *MutuallyExclusiveConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *MutuallyExclusiveConstraint) Anchor() bool {
+	return false
+}
+
+// MutuallyExclusive returns a Constraint that forbids solutions that
+// contain more than one of the Variables identified by the given
+// Identifiers. It does not depend on its subject, and is intended to
+// be used as a global constraint of a deppy.Problem.
+func MutuallyExclusive(ids ...deppy.Identifier) deppy.Constraint {
+	return &MutuallyExclusiveConstraint{
+		IDs: ids,
+	}
+}
+
+type IffConstraint struct {
+	OperandID deppy.Identifier `json:"operandID"`
+	// IsOperandNegated inverts the relation, so that exactly one
+	// of the subject and the operand is selected.
+	IsOperandNegated bool `json:"isOperandNegated,omitempty"`
+}
+
+func (constraint *IffConstraint) String(subject deppy.Identifier) string {
+	if constraint.IsOperandNegated {
+		return fmt.Sprintf("%s is selected if and only if %s is not selected", subject, constraint.OperandID)
+	}
+	return fmt.Sprintf("%s is selected if and only if %s is selected", subject, constraint.OperandID)
+}
+
+func (constraint *IffConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	operandLit := lm.LitOf(constraint.OperandID)
+	if !constraint.IsOperandNegated {
+		operandLit = operandLit.Not()
+	}
+	return lm.LogicCircuit().Xor(lm.LitOf(subject), operandLit)
+}
+
+func (constraint *IffConstraint) Order() []deppy.Identifier {
+	if constraint.IsOperandNegated {
+		return nil
+	}
+	return []deppy.Identifier{constraint.OperandID}
+}
+
+func (constraint *IffConstraint) Anchor() bool {
+	return false
+}
+
+// Iff returns a Constraint that will only permit solutions that
+// either contain both a given Variable and the Variable identified
+// by the given Identifier, or neither of them.
+func Iff(id deppy.Identifier) deppy.Constraint {
+	return &IffConstraint{
+		OperandID: id,
+	}
+}
+
+// Xor returns a Constraint that will only permit solutions that
+// contain exactly one of a given Variable and the Variable identified
+// by the given Identifier.
+func Xor(id deppy.Identifier) deppy.Constraint {
+	return &IffConstraint{
+		OperandID:        id,
+		IsOperandNegated: true,
+	}
+}
+
 type OrConstraint struct {
-	Operand          deppy.Identifier
-	IsSubjectNegated bool
-	IsOperandNegated bool
+	Operand          deppy.Identifier `json:"operand"`
+	IsSubjectNegated bool             `json:"isSubjectNegated,omitempty"`
+	IsOperandNegated bool             `json:"isOperandNegated,omitempty"`
 }
 
 func (constraint *OrConstraint) String(subject deppy.Identifier) string {
@@ -232,3 +988,157 @@ func Or(identifier deppy.Identifier, isSubjectNegated bool, isOperandNegated boo
 		IsOperandNegated: isOperandNegated,
 	}
 }
+
+// Literal refers to a Variable that is selected or, if Negated, to a
+// Variable that is not selected.
+type Literal struct {
+	ID      deppy.Identifier `json:"id"`
+	Negated bool             `json:"negated,omitempty"`
+}
+
+func (l Literal) String() string {
+	if l.Negated {
+		return "!" + string(l.ID)
+	}
+	return string(l.ID)
+}
+
+// Positive returns a Literal that holds if the Variable identified by
+// the given Identifier is selected.
+func Positive(id deppy.Identifier) Literal {
+	return Literal{ID: id}
+}
+
+// Negative returns a Literal that holds if the Variable identified by
+// the given Identifier is not selected.
+func Negative(id deppy.Identifier) Literal {
+	return Literal{ID: id, Negated: true}
+}
+
+type ClauseConstraint struct {
+	Literals []Literal `json:"literals"`
+}
+
+// String describes the clause without reference to the subject, since
+// the constraint does not depend on it.
+func (constraint *ClauseConstraint) String(_ deppy.Identifier) string {
+	if len(constraint.Literals) == 0 {
+		return "the empty clause can never hold"
+	}
+	s := make([]string, len(constraint.Literals))
+	for i, each := range constraint.Literals {
+		s[i] = each.String()
+	}
+	return fmt.Sprintf("at least one of %s must hold", strings.Join(s, ", "))
+}
+
+func (constraint *ClauseConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.Literals))
+	for i, each := range constraint.Literals {
+		ms[i] = lm.LitOf(each.ID)
+		if each.Negated {
+			ms[i] = ms[i].Not()
+		}
+	}
+	return lm.LogicCircuit().Ors(ms...)
+}
+
+func (constraint *ClauseConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *ClauseConstraint) Anchor() bool {
+	return false
+}
+
+// Clause returns a Constraint that permits only solutions in which at
+// least one of the given Literals holds, so that a formula in
+// conjunctive normal form translates to one Clause per clause. An
+// empty Clause permits no solution. Clause does not depend on its
+// subject, and is intended to be used as a global constraint of a
+// deppy.Problem.
+func Clause(literals ...Literal) deppy.Constraint {
+	return &ClauseConstraint{
+		Literals: literals,
+	}
+}
+
+type ExprConstraint struct {
+	Expression expr.Node
+}
+
+func (constraint *ExprConstraint) String(subject deppy.Identifier) string {
+	if subject == "" {
+		return fmt.Sprintf("%s must hold", constraint.Expression.Format(""))
+	}
+	return fmt.Sprintf("%s requires that %s", subject, constraint.Expression.Format(string(subject)))
+}
+
+func (constraint *ExprConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return constraint.Expression.Lit(lm, subject)
+}
+
+// Order returns the candidates of the expression (see expr.Node) in
+// the order they first appear, so that the solver prefers to satisfy
+// the expression by selecting Variables mentioned earlier.
+func (constraint *ExprConstraint) Order() []deppy.Identifier {
+	var ids []deppy.Identifier
+	seen := map[deppy.Identifier]struct{}{}
+	for _, id := range constraint.Expression.Candidates(nil) {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (constraint *ExprConstraint) Anchor() bool {
+	return false
+}
+
+// MarshalJSON encodes the expression in the syntax of expr.Parse.
+func (constraint *ExprConstraint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(exprConstraintEncoding{
+		Expression: constraint.Expression.Format(expr.SubjectName),
+	})
+}
+
+func (constraint *ExprConstraint) UnmarshalJSON(data []byte) error {
+	var encoding exprConstraintEncoding
+	if err := UnmarshalStrict(data, &encoding); err != nil {
+		return err
+	}
+	expression, err := expr.Parse(encoding.Expression)
+	if err != nil {
+		return fmt.Errorf("invalid expression %q: %w", encoding.Expression, err)
+	}
+	constraint.Expression = expression
+	return nil
+}
+
+type exprConstraintEncoding struct {
+	Expression string `json:"expression"`
+}
+
+// Expr returns a Constraint that will only permit solutions that
+// satisfy the given boolean expression. References to the subject
+// within the expression refer to the Variable the Constraint is
+// applied to.
+func Expr(expression expr.Node) deppy.Constraint {
+	return &ExprConstraint{
+		Expression: expression,
+	}
+}
+
+// ParseExpr returns a Constraint that will only permit solutions that
+// satisfy the boolean expression described by s, such as
+// "subject -> (b & c) | d". See expr.Parse for the syntax.
+func ParseExpr(s string) (deppy.Constraint, error) {
+	expression, err := expr.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", s, err)
+	}
+	return Expr(expression), nil
+}
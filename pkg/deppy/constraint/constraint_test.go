@@ -2,12 +2,16 @@ package constraint_test
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
 )
@@ -17,6 +21,60 @@ func TestPkg(t *testing.T) {
 	RunSpecs(t, "Constraint Suite")
 }
 
+// testLitMapping is a deppy.LitMapping over a fixed set of
+// Identifiers that can evaluate the literals produced by constraints.
+type testLitMapping struct {
+	c    *logic.C
+	lits map[deppy.Identifier]z.Lit
+}
+
+func newTestLitMapping(ids ...deppy.Identifier) *testLitMapping {
+	lm := &testLitMapping{
+		c:    logic.NewC(),
+		lits: map[deppy.Identifier]z.Lit{},
+	}
+	for _, id := range ids {
+		lm.lits[id] = lm.c.Lit()
+	}
+	return lm
+}
+
+func (lm *testLitMapping) LitOf(id deppy.Identifier) z.Lit {
+	m, ok := lm.lits[id]
+	Expect(ok).To(BeTrue(), "unexpected identifier %q", id)
+	return m
+}
+
+func (lm *testLitMapping) LogicCircuit() *logic.C {
+	return lm.c
+}
+
+// Eval returns the value of m when exactly the Variables with the
+// given Identifiers are selected.
+func (lm *testLitMapping) Eval(m z.Lit, selected ...deppy.Identifier) bool {
+	vs := make([]bool, lm.c.Len())
+	for _, id := range selected {
+		vs[lm.LitOf(id).Var()] = true
+	}
+	lm.c.Eval(vs)
+	return vs[m.Var()] == m.IsPos()
+}
+
+// subsets returns every subset of the given Identifiers.
+func subsets(ids ...deppy.Identifier) [][]deppy.Identifier {
+	result := make([][]deppy.Identifier, 0, 1<<len(ids))
+	for mask := 0; mask < 1<<len(ids); mask++ {
+		var subset []deppy.Identifier
+		for i, id := range ids {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, id)
+			}
+		}
+		result = append(result, subset)
+	}
+	return result
+}
+
 var _ = Describe("Constraint", func() {
 	Describe("UserFriendlyConstraint", func() {
 		It("should provide the custom constraint message", func() {
@@ -26,4 +84,412 @@ var _ = Describe("Constraint", func() {
 			Expect(userFriendlyConstraint.String("this thing")).To(Equal("'this thing' just _has_ to be there or you can't even..."))
 		})
 	})
+
+	Describe("Optional", func() {
+		c := constraint.Optional(3)
+
+		It("should describe the request", func() {
+			Expect(c.String("a")).To(Equal("a is requested with priority 3"))
+		})
+		It("should be a soft anchor", func() {
+			Expect(c.Anchor()).To(BeTrue())
+			priority, ok := deppy.PriorityOf(c)
+			Expect(ok).To(BeTrue())
+			Expect(priority).To(Equal(3))
+		})
+		It("should remain soft when wrapped", func() {
+			priority, ok := deppy.PriorityOf(constraint.WithMetadata(c, deppy.Metadata{Origin: "user request"}))
+			Expect(ok).To(BeTrue())
+			Expect(priority).To(Equal(3))
+		})
+		It("should require its subject", func() {
+			lm := newTestLitMapping("a")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m)).To(BeFalse())
+			Expect(lm.Eval(m, "a")).To(BeTrue())
+		})
+		It("should not make other constraints soft", func() {
+			_, ok := deppy.PriorityOf(constraint.Mandatory())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Recommends", func() {
+		c := constraint.Recommends("x", "y")
+
+		It("should describe the recommendation", func() {
+			Expect(c.String("a")).To(Equal("a recommends at least one of x, y"))
+		})
+		It("should prefer the recommended variables in order", func() {
+			Expect(c.Order()).To(Equal([]deppy.Identifier{"x", "y"}))
+		})
+		It("should be softer than any optional anchor", func() {
+			priority, ok := deppy.PriorityOf(c)
+			Expect(ok).To(BeTrue())
+			Expect(priority).To(BeNumerically("<", constraint.Optional(-1000).(deppy.SoftConstraint).Priority()))
+		})
+		It("should apply like a dependency", func() {
+			lm := newTestLitMapping("a", "x", "y")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m)).To(BeTrue())
+			Expect(lm.Eval(m, "a")).To(BeFalse())
+			Expect(lm.Eval(m, "a", "y")).To(BeTrue())
+		})
+	})
+
+	Describe("WithMetadata", func() {
+		metadata := deppy.Metadata{Origin: "manifest", Labels: map[string]string{"bundle": "x.v1"}}
+		c := constraint.WithMetadata(constraint.Implies("x", "y"), metadata)
+
+		It("should carry its metadata", func() {
+			Expect(c.Metadata()).To(Equal(metadata))
+			Expect(deppy.MetadataOf(c)).To(Equal(metadata))
+		})
+		It("should not change the message of the constraint", func() {
+			Expect(c.String("a")).To(Equal("a requires all of x, y"))
+		})
+		It("should preserve the choices of the constraint", func() {
+			Expect(c.Choices()).To(Equal([][]deppy.Identifier{{"x"}, {"y"}}))
+			Expect(constraint.WithMetadata(constraint.Dependency("x", "y"), metadata).Choices()).To(Equal([][]deppy.Identifier{{"x", "y"}}))
+		})
+		It("should be visible through a user friendly constraint", func() {
+			uf := constraint.NewUserFriendlyConstraint(c, func(_ deppy.Constraint, subject deppy.Identifier) string {
+				return fmt.Sprintf("%s needs x and y", subject)
+			})
+			Expect(deppy.MetadataOf(uf)).To(Equal(metadata))
+			Expect(deppy.AppliedConstraint{Variable: input.NewSimpleVariable("a"), Constraint: uf}.String()).To(Equal("a needs x and y (origin: manifest; labels: bundle=x.v1)"))
+		})
+		It("should keep its choices through a user friendly constraint", func() {
+			uf := constraint.NewUserFriendlyConstraint(c, func(_ deppy.Constraint, subject deppy.Identifier) string {
+				return fmt.Sprintf("%s needs x and y", subject)
+			})
+			Expect(uf.Choices()).To(Equal([][]deppy.Identifier{{"x"}, {"y"}}))
+		})
+		It("should be visible through several wrappers", func() {
+			uf := constraint.NewUserFriendlyConstraint(constraint.NewUserFriendlyConstraint(c, func(_ deppy.Constraint, subject deppy.Identifier) string {
+				return fmt.Sprintf("%s needs x and y", subject)
+			}), func(_ deppy.Constraint, subject deppy.Identifier) string {
+				return fmt.Sprintf("%s really needs x and y", subject)
+			})
+			Expect(deppy.MetadataOf(uf)).To(Equal(metadata))
+			Expect(deppy.AppliedConstraint{Variable: input.NewSimpleVariable("a"), Constraint: uf}.String()).To(Equal("a really needs x and y (origin: manifest; labels: bundle=x.v1)"))
+		})
+		It("should not invent metadata", func() {
+			Expect(deppy.MetadataOf(constraint.Mandatory()).IsZero()).To(BeTrue())
+		})
+	})
+
+	Describe("Implies", func() {
+		c := constraint.Implies("x", "y")
+
+		It("should describe the requirement", func() {
+			Expect(c.String("a")).To(Equal("a requires all of x, y"))
+		})
+		It("should prefer each implied variable separately", func() {
+			Expect(c.Order()).To(Equal([]deppy.Identifier{"x", "y"}))
+			Expect(c.(deppy.ChoiceConstraint).Choices()).To(Equal([][]deppy.Identifier{{"x"}, {"y"}}))
+		})
+		It("should require every implied variable", func() {
+			lm := newTestLitMapping("a", "x", "y")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m)).To(BeTrue())
+			Expect(lm.Eval(m, "x")).To(BeTrue())
+			Expect(lm.Eval(m, "a", "x")).To(BeFalse())
+			Expect(lm.Eval(m, "a", "y")).To(BeFalse())
+			Expect(lm.Eval(m, "a", "x", "y")).To(BeTrue())
+		})
+	})
+
+	Describe("Excludes", func() {
+		c := constraint.Excludes("x", "y")
+
+		It("should describe the exclusion", func() {
+			Expect(c.String("a")).To(Equal("a excludes all of x, y"))
+		})
+		It("should not express a preference", func() {
+			Expect(c.Order()).To(BeEmpty())
+		})
+		It("should forbid every excluded variable", func() {
+			lm := newTestLitMapping("a", "x", "y")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m, "a")).To(BeTrue())
+			Expect(lm.Eval(m, "x", "y")).To(BeTrue())
+			Expect(lm.Eval(m, "a", "x")).To(BeFalse())
+			Expect(lm.Eval(m, "a", "y")).To(BeFalse())
+		})
+	})
+
+	Describe("ConflictsWithAny", func() {
+		It("should describe the whole set in a single message", func() {
+			Expect(constraint.ConflictsWithAny("x", "y").String("a")).To(Equal("a excludes all of x, y"))
+			Expect(constraint.RequiresNoneOf("x", "y")).To(Equal(constraint.ConflictsWithAny("x", "y")))
+		})
+	})
+
+	Describe("MutuallyExclusive", func() {
+		c := constraint.MutuallyExclusive("x", "y", "z")
+
+		It("should describe the group regardless of the subject", func() {
+			Expect(c.String("a")).To(Equal("at most one of x, y, z may be selected"))
+			Expect(c.String("")).To(Equal("at most one of x, y, z may be selected"))
+		})
+		It("should not express a preference", func() {
+			Expect(c.Order()).To(BeEmpty())
+		})
+		It("should permit at most one member of the group", func() {
+			lm := newTestLitMapping("x", "y", "z")
+			m := c.Apply(lm, "")
+			for _, selected := range subsets("x", "y", "z") {
+				Expect(lm.Eval(m, selected...)).To(Equal(len(selected) <= 1), "selected: %v", selected)
+			}
+		})
+	})
+
+	Describe("Clause", func() {
+		c := constraint.Clause(constraint.Positive("x"), constraint.Negative("y"), constraint.Positive("z"))
+
+		It("should describe the literals regardless of the subject", func() {
+			Expect(c.String("a")).To(Equal("at least one of x, !y, z must hold"))
+			Expect(c.String("")).To(Equal("at least one of x, !y, z must hold"))
+			Expect(constraint.Clause().String("")).To(Equal("the empty clause can never hold"))
+		})
+		It("should not express a preference", func() {
+			Expect(c.Order()).To(BeEmpty())
+		})
+		It("should permit exactly the assignments satisfying a literal", func() {
+			lm := newTestLitMapping("x", "y", "z")
+			m := c.Apply(lm, "")
+			for _, selected := range subsets("x", "y", "z") {
+				expected := len(selected) != 1 || selected[0] != "y"
+				Expect(lm.Eval(m, selected...)).To(Equal(expected), "selected: %v", selected)
+			}
+		})
+		It("should permit nothing when empty", func() {
+			lm := newTestLitMapping("x")
+			m := constraint.Clause().Apply(lm, "")
+			Expect(lm.Eval(m)).To(BeFalse())
+			Expect(lm.Eval(m, "x")).To(BeFalse())
+		})
+	})
+
+	Describe("Iff", func() {
+		c := constraint.Iff("x")
+
+		It("should describe the equivalence", func() {
+			Expect(c.String("a")).To(Equal("a is selected if and only if x is selected"))
+		})
+		It("should prefer the operand", func() {
+			Expect(c.Order()).To(Equal([]deppy.Identifier{"x"}))
+		})
+		It("should require both or neither", func() {
+			lm := newTestLitMapping("a", "x")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m)).To(BeTrue())
+			Expect(lm.Eval(m, "a", "x")).To(BeTrue())
+			Expect(lm.Eval(m, "a")).To(BeFalse())
+			Expect(lm.Eval(m, "x")).To(BeFalse())
+		})
+	})
+
+	Describe("Xor", func() {
+		c := constraint.Xor("x")
+
+		It("should describe the exclusive choice", func() {
+			Expect(c.String("a")).To(Equal("a is selected if and only if x is not selected"))
+		})
+		It("should not express a preference", func() {
+			Expect(c.Order()).To(BeEmpty())
+		})
+		It("should require exactly one", func() {
+			lm := newTestLitMapping("a", "x")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m)).To(BeFalse())
+			Expect(lm.Eval(m, "a", "x")).To(BeFalse())
+			Expect(lm.Eval(m, "a")).To(BeTrue())
+			Expect(lm.Eval(m, "x")).To(BeTrue())
+		})
+	})
+
+	DescribeTable("cardinality constraints",
+		func(c deppy.Constraint, message string, lo, hi int) {
+			Expect(c.String("a")).To(Equal(message))
+
+			lm := newTestLitMapping("a", "x", "y", "z")
+			m := c.Apply(lm, "a")
+			for _, selected := range subsets("x", "y", "z") {
+				Expect(lm.Eval(m, selected...)).To(Equal(len(selected) >= lo && len(selected) <= hi), "selected: %v", selected)
+			}
+		},
+		Entry("at most", constraint.AtMost(1, "x", "y", "z"), "a permits at most 1 of x, y, z", 0, 1),
+		Entry("at least", constraint.AtLeast(2, "x", "y", "z"), "a requires at least 2 of x, y, z", 2, 3),
+		Entry("at least none", constraint.AtLeast(0, "x", "y", "z"), "a requires at least 0 of x, y, z", 0, 3),
+		Entry("exactly", constraint.Exactly(2, "x", "y", "z"), "a requires exactly 2 of x, y, z", 2, 2),
+		Entry("between", constraint.Between(1, 2, "x", "y", "z"), "a requires between 1 and 2 of x, y, z", 1, 2),
+		Entry("unsatisfiable range", constraint.Between(2, 1, "x", "y", "z"), "a requires between 2 and 1 of x, y, z", 2, 1),
+	)
+
+	DescribeTable("global cardinality constraints",
+		func(c deppy.Constraint, message string) {
+			Expect(c.String("")).To(Equal(message))
+		},
+		Entry("at most", constraint.AtMost(1, "x", "y", "z"), "at most 1 of x, y, z may be selected"),
+		Entry("at least", constraint.AtLeast(2, "x", "y", "z"), "at least 2 of x, y, z must be selected"),
+		Entry("exactly", constraint.Exactly(2, "x", "y", "z"), "exactly 2 of x, y, z must be selected"),
+		Entry("between", constraint.Between(1, 2, "x", "y", "z"), "between 1 and 2 of x, y, z must be selected"),
+		Entry("weighted at most", constraint.WeightedAtMost(3, map[deppy.Identifier]int{"x": 2, "y": 1}), "a total weight of at most 3 may be selected from x (2), y (1)"),
+		Entry("weighted at least", constraint.WeightedAtLeast(3, map[deppy.Identifier]int{"x": 2, "y": 1}), "a total weight of at least 3 must be selected from x (2), y (1)"),
+	)
+
+	DescribeTable("cardinality preferences",
+		func(c deppy.Constraint, expected []deppy.Identifier) {
+			Expect(c.Order()).To(Equal(expected))
+		},
+		Entry("at least", constraint.AtLeast(1, "x", "y"), []deppy.Identifier{"x", "y"}),
+		Entry("at least none", constraint.AtLeast(0, "x", "y"), nil),
+		Entry("exactly", constraint.Exactly(1, "x", "y"), []deppy.Identifier{"x", "y"}),
+		Entry("between", constraint.Between(0, 1, "x", "y"), nil),
+	)
+
+	DescribeTable("weighted constraints",
+		func(c deppy.Constraint, message string, weights map[deppy.Identifier]int, holds func(sum int) bool) {
+			Expect(c.String("a")).To(Equal(message))
+
+			lm := newTestLitMapping("a", "w", "x", "y", "z")
+			m := c.Apply(lm, "a")
+			for _, selected := range subsets("w", "x", "y", "z") {
+				var sum int
+				for _, id := range selected {
+					sum += weights[id]
+				}
+				Expect(lm.Eval(m, selected...)).To(Equal(holds(sum)), "selected: %v", selected)
+			}
+		},
+		Entry("at most",
+			constraint.WeightedAtMost(6, map[deppy.Identifier]int{"w": 1, "x": 2, "y": 3, "z": 4}),
+			"a permits a total weight of at most 6 from w (1), x (2), y (3), z (4)",
+			map[deppy.Identifier]int{"w": 1, "x": 2, "y": 3, "z": 4},
+			func(sum int) bool { return sum <= 6 },
+		),
+		Entry("at most with equal weights",
+			constraint.WeightedAtMost(9, map[deppy.Identifier]int{"w": 5, "x": 5, "y": 4, "z": 4}),
+			"a permits a total weight of at most 9 from w (5), x (5), y (4), z (4)",
+			map[deppy.Identifier]int{"w": 5, "x": 5, "y": 4, "z": 4},
+			func(sum int) bool { return sum <= 9 },
+		),
+		Entry("at most with negative and zero weights",
+			constraint.WeightedAtMost(1, map[deppy.Identifier]int{"w": -2, "x": 0, "y": 3, "z": 1}),
+			"a permits a total weight of at most 1 from w (-2), x (0), y (3), z (1)",
+			map[deppy.Identifier]int{"w": -2, "x": 0, "y": 3, "z": 1},
+			func(sum int) bool { return sum <= 1 },
+		),
+		Entry("at most a negative limit",
+			constraint.WeightedAtMost(-1, map[deppy.Identifier]int{"w": -2, "x": 1}),
+			"a permits a total weight of at most -1 from w (-2), x (1)",
+			map[deppy.Identifier]int{"w": -2, "x": 1},
+			func(sum int) bool { return sum <= -1 },
+		),
+		Entry("at least",
+			constraint.WeightedAtLeast(5, map[deppy.Identifier]int{"w": 1, "x": 2, "y": 3, "z": 4}),
+			"a requires a total weight of at least 5 from w (1), x (2), y (3), z (4)",
+			map[deppy.Identifier]int{"w": 1, "x": 2, "y": 3, "z": 4},
+			func(sum int) bool { return sum >= 5 },
+		),
+		Entry("at least with negative weights",
+			constraint.WeightedAtLeast(2, map[deppy.Identifier]int{"w": -3, "x": 2, "y": 3, "z": -1}),
+			"a requires a total weight of at least 2 from w (-3), x (2), y (3), z (-1)",
+			map[deppy.Identifier]int{"w": -3, "x": 2, "y": 3, "z": -1},
+			func(sum int) bool { return sum >= 2 },
+		),
+		Entry("at most with large weights",
+			constraint.WeightedAtMost(3<<52, map[deppy.Identifier]int{"w": 1 << 52, "x": 2 << 52, "y": -(3 << 52), "z": 4 << 52}),
+			"a permits a total weight of at most 13510798882111488 from w (4503599627370496), x (9007199254740992), y (-13510798882111488), z (18014398509481984)",
+			map[deppy.Identifier]int{"w": 1 << 52, "x": 2 << 52, "y": -(3 << 52), "z": 4 << 52},
+			func(sum int) bool { return sum <= 3<<52 },
+		),
+		Entry("at least more than the total",
+			constraint.WeightedAtLeast(11, map[deppy.Identifier]int{"w": 1, "x": 2, "y": 3, "z": 4}),
+			"a requires a total weight of at least 11 from w (1), x (2), y (3), z (4)",
+			map[deppy.Identifier]int{"w": 1, "x": 2, "y": 3, "z": 4},
+			func(sum int) bool { return sum >= 11 },
+		),
+	)
+
+	It("should encode weighted constraints independently of the magnitude of the weights", func() {
+		size := func(scale int) int {
+			weights := map[deppy.Identifier]int{}
+			var ids []deppy.Identifier
+			for i := 0; i < 100; i++ {
+				id := deppy.Identifier(fmt.Sprintf("v%03d", i))
+				ids = append(ids, id)
+				weights[id] = (1000 + i%7) * scale
+			}
+			lm := newTestLitMapping(ids...)
+			before := lm.c.Len()
+			constraint.WeightedAtMost(50*1003*scale, weights).Apply(lm, "")
+			return lm.c.Len() - before
+		}
+		// far fewer gates than the terms times the limit
+		small := size(1)
+		Expect(small).To(BeNumerically("<", 100*1003))
+		Expect(size(1 << 40)).To(Equal(small))
+	})
+
+	It("should reject weights whose sum overflows", func() {
+		c := constraint.WeightedAtMost(1, map[deppy.Identifier]int{"x": math.MaxInt / 2, "y": math.MaxInt / 2, "z": 2})
+		Expect(deppy.Validate(c)).To(MatchError("weights and limit overflow at z (2)"))
+		c = constraint.WeightedAtLeast(math.MinInt, map[deppy.Identifier]int{"x": 1})
+		Expect(deppy.Validate(c)).To(MatchError(fmt.Sprintf("limit %d is out of range", math.MinInt)))
+		c = constraint.WeightedAtMost(-2, map[deppy.Identifier]int{"x": math.MinInt})
+		Expect(deppy.Validate(c)).To(MatchError(fmt.Sprintf("weights and limit overflow at x (%d)", math.MinInt)))
+		Expect(deppy.Validate(constraint.WithMetadata(c, deppy.Metadata{Origin: "test"}))).To(HaveOccurred())
+		Expect(deppy.Validate(constraint.WeightedAtMost(math.MaxInt-1, map[deppy.Identifier]int{"x": 1}))).To(Succeed())
+
+		// the literal of an invalid constraint is false
+		lm := newTestLitMapping("x", "y", "z")
+		m := constraint.WeightedAtMost(1, map[deppy.Identifier]int{"x": math.MaxInt / 2, "y": math.MaxInt / 2, "z": 2}).Apply(lm, "")
+		Expect(lm.Eval(m)).To(BeFalse())
+	})
+
+	DescribeTable("weighted preferences",
+		func(c deppy.Constraint, expected []deppy.Identifier) {
+			Expect(c.Order()).To(Equal(expected))
+		},
+		Entry("at most", constraint.WeightedAtMost(1, map[deppy.Identifier]int{"x": 1, "y": 2}), nil),
+		Entry("at least", constraint.WeightedAtLeast(1, map[deppy.Identifier]int{"w": 1, "x": 2, "y": -3, "z": 2}), []deppy.Identifier{"x", "z", "w"}),
+		Entry("at least none", constraint.WeightedAtLeast(0, map[deppy.Identifier]int{"x": 1, "y": 2}), nil),
+	)
+
+	Describe("Expr", func() {
+		It("should reject invalid expressions", func() {
+			_, err := constraint.ParseExpr("subject -> (b")
+			Expect(err).To(MatchError(`invalid expression "subject -> (b": unexpected end of expression`))
+		})
+
+		c, err := constraint.ParseExpr("subject -> (b & c) | !d | b")
+
+		It("should parse", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should describe the expression in terms of the subject", func() {
+			Expect(c.String("a")).To(Equal("a requires that a -> b & c | !d | b"))
+		})
+		It("should describe a global expression without a subject", func() {
+			global, err := constraint.ParseExpr("b -> c | d")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(global.String("")).To(Equal("b -> c | d must hold"))
+		})
+		It("should prefer the variables that satisfy the implication", func() {
+			Expect(c.Order()).To(Equal([]deppy.Identifier{"b", "c"}))
+		})
+		It("should apply the expression to the subject", func() {
+			lm := newTestLitMapping("a", "b", "c", "d")
+			m := c.Apply(lm, "a")
+			Expect(lm.Eval(m, "d")).To(BeTrue())
+			Expect(lm.Eval(m, "a")).To(BeTrue())
+			Expect(lm.Eval(m, "a", "d")).To(BeFalse())
+			Expect(lm.Eval(m, "a", "c", "d")).To(BeFalse())
+			Expect(lm.Eval(m, "a", "b", "d")).To(BeTrue())
+			Expect(lm.Eval(m, "a", "b", "c", "d")).To(BeTrue())
+		})
+	})
 })
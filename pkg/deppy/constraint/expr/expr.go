@@ -0,0 +1,259 @@
+// Package expr implements boolean expressions over the Variables of a
+// problem, for use in constraints that cannot be expressed with the
+// fixed-form constraints of the constraint package.
+package expr
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-air/gini/z"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// Node is a boolean expression. Expressions may refer to particular
+// Variables by Identifier, or to the subject of the constraint that
+// the expression is applied through.
+type Node interface {
+	// Lit returns a literal that is true exactly when the
+	// expression holds.
+	Lit(lm deppy.LitMapping, subject deppy.Identifier) z.Lit
+	// Candidates appends to dst the Identifiers of Variables whose
+	// selection may satisfy the expression, in the order they
+	// appear. Variables that only occur negated or in the premise
+	// of an implication are not candidates.
+	Candidates(dst []deppy.Identifier) []deppy.Identifier
+	// Format renders the expression, substituting name for any
+	// reference to the subject.
+	Format(name string) string
+	precedence() int
+}
+
+const (
+	precedenceImplies = iota + 1
+	precedenceOr
+	precedenceAnd
+	precedenceNot
+	precedenceAtom
+)
+
+// SubjectName is how references to the subject are rendered and
+// parsed.
+const SubjectName = "subject"
+
+// Var is an expression that holds when the Variable with the given
+// Identifier is selected.
+type Var deppy.Identifier
+
+func (v Var) Lit(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	return lm.LitOf(deppy.Identifier(v))
+}
+
+func (v Var) Candidates(dst []deppy.Identifier) []deppy.Identifier {
+	return append(dst, deppy.Identifier(v))
+}
+
+func (v Var) Format(_ string) string {
+	return quote(string(v))
+}
+
+func (v Var) String() string {
+	return v.Format(SubjectName)
+}
+
+func (Var) precedence() int {
+	return precedenceAtom
+}
+
+// Subject is an expression that holds when the subject of the
+// constraint is selected.
+type Subject struct{}
+
+func (Subject) Lit(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LitOf(subject)
+}
+
+func (Subject) Candidates(dst []deppy.Identifier) []deppy.Identifier {
+	return dst
+}
+
+func (Subject) Format(name string) string {
+	return name
+}
+
+func (s Subject) String() string {
+	return s.Format(SubjectName)
+}
+
+func (Subject) precedence() int {
+	return precedenceAtom
+}
+
+// Not is an expression that holds when its operand does not.
+type Not struct {
+	Operand Node
+}
+
+func (n Not) Lit(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return n.Operand.Lit(lm, subject).Not()
+}
+
+func (Not) Candidates(dst []deppy.Identifier) []deppy.Identifier {
+	return dst
+}
+
+func (n Not) Format(name string) string {
+	return "!" + format(n.Operand, name, precedenceNot)
+}
+
+func (n Not) String() string {
+	return n.Format(SubjectName)
+}
+
+func (Not) precedence() int {
+	return precedenceNot
+}
+
+// And is an expression that holds when all of its operands hold. An
+// empty And always holds.
+type And []Node
+
+func (a And) Lit(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(a))
+	for i, each := range a {
+		ms[i] = each.Lit(lm, subject)
+	}
+	return lm.LogicCircuit().Ands(ms...)
+}
+
+func (a And) Candidates(dst []deppy.Identifier) []deppy.Identifier {
+	for _, each := range a {
+		dst = each.Candidates(dst)
+	}
+	return dst
+}
+
+func (a And) Format(name string) string {
+	if len(a) == 0 {
+		return "true"
+	}
+	s := make([]string, len(a))
+	for i, each := range a {
+		s[i] = format(each, name, precedenceAnd+1)
+	}
+	return strings.Join(s, " & ")
+}
+
+func (a And) String() string {
+	return a.Format(SubjectName)
+}
+
+func (a And) precedence() int {
+	if len(a) < 2 {
+		return precedenceAtom
+	}
+	return precedenceAnd
+}
+
+// Or is an expression that holds when at least one of its operands
+// holds. An empty Or never holds.
+type Or []Node
+
+func (o Or) Lit(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(o))
+	for i, each := range o {
+		ms[i] = each.Lit(lm, subject)
+	}
+	return lm.LogicCircuit().Ors(ms...)
+}
+
+func (o Or) Candidates(dst []deppy.Identifier) []deppy.Identifier {
+	for _, each := range o {
+		dst = each.Candidates(dst)
+	}
+	return dst
+}
+
+func (o Or) Format(name string) string {
+	if len(o) == 0 {
+		return "false"
+	}
+	s := make([]string, len(o))
+	for i, each := range o {
+		s[i] = format(each, name, precedenceOr+1)
+	}
+	return strings.Join(s, " | ")
+}
+
+func (o Or) String() string {
+	return o.Format(SubjectName)
+}
+
+func (o Or) precedence() int {
+	if len(o) < 2 {
+		return precedenceAtom
+	}
+	return precedenceOr
+}
+
+// Implies is an expression that holds unless If holds and Then does
+// not.
+type Implies struct {
+	If, Then Node
+}
+
+func (i Implies) Lit(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Implies(i.If.Lit(lm, subject), i.Then.Lit(lm, subject))
+}
+
+func (i Implies) Candidates(dst []deppy.Identifier) []deppy.Identifier {
+	return i.Then.Candidates(dst)
+}
+
+func (i Implies) Format(name string) string {
+	// Implication is right-associative.
+	return format(i.If, name, precedenceImplies+1) + " -> " + format(i.Then, name, precedenceImplies)
+}
+
+func (i Implies) String() string {
+	return i.Format(SubjectName)
+}
+
+func (Implies) precedence() int {
+	return precedenceImplies
+}
+
+// format renders n, parenthesized if it binds less tightly than min.
+func format(n Node, name string, min int) string {
+	if n.precedence() < min {
+		return "(" + n.Format(name) + ")"
+	}
+	return n.Format(name)
+}
+
+// quote returns id as it must be written in an expression to be
+// parsed back as a reference to the Variable with that Identifier.
+func quote(id string) string {
+	if id == "" || isKeyword(id) {
+		return strconv.Quote(id)
+	}
+	for i := 0; i < len(id); i++ {
+		if !isIdentifierByte(id[i]) || (id[i] == '-' && i+1 < len(id) && id[i+1] == '>') {
+			return strconv.Quote(id)
+		}
+	}
+	return id
+}
+
+func isKeyword(s string) bool {
+	return s == SubjectName || s == "true" || s == "false"
+}
+
+func isIdentifierByte(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("_.-+:/@", b) >= 0
+}
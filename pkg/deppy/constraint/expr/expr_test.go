@@ -0,0 +1,176 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint/expr"
+)
+
+type testLitMapping struct {
+	c    *logic.C
+	lits map[deppy.Identifier]z.Lit
+}
+
+func (lm *testLitMapping) LitOf(id deppy.Identifier) z.Lit {
+	m, ok := lm.lits[id]
+	if !ok {
+		m = lm.c.Lit()
+		lm.lits[id] = m
+	}
+	return m
+}
+
+func (lm *testLitMapping) LogicCircuit() *logic.C {
+	return lm.c
+}
+
+func TestParse(t *testing.T) {
+	type tc struct {
+		Name     string
+		Input    string
+		Expected expr.Node
+		String   string
+	}
+
+	for _, tt := range []tc{
+		{
+			Name:     "identifier",
+			Input:    "a",
+			Expected: expr.Var("a"),
+			String:   "a",
+		},
+		{
+			Name:     "subject",
+			Input:    "subject",
+			Expected: expr.Subject{},
+			String:   "subject",
+		},
+		{
+			Name:     "constants",
+			Input:    "true | false",
+			Expected: expr.Or{expr.And{}, expr.Or{}},
+			String:   "true | false",
+		},
+		{
+			Name:  "precedence",
+			Input: "subject -> (b & c) | d",
+			Expected: expr.Implies{
+				If:   expr.Subject{},
+				Then: expr.Or{expr.And{expr.Var("b"), expr.Var("c")}, expr.Var("d")},
+			},
+			String: "subject -> b & c | d",
+		},
+		{
+			Name:  "grouping overrides precedence",
+			Input: "a & (b | c)",
+			Expected: expr.And{
+				expr.Var("a"),
+				expr.Or{expr.Var("b"), expr.Var("c")},
+			},
+			String: "a & (b | c)",
+		},
+		{
+			Name:  "implication is right-associative",
+			Input: "a -> b -> c",
+			Expected: expr.Implies{
+				If:   expr.Var("a"),
+				Then: expr.Implies{If: expr.Var("b"), Then: expr.Var("c")},
+			},
+			String: "a -> b -> c",
+		},
+		{
+			Name:  "left-nested implication is grouped",
+			Input: "(a -> b) -> c",
+			Expected: expr.Implies{
+				If:   expr.Implies{If: expr.Var("a"), Then: expr.Var("b")},
+				Then: expr.Var("c"),
+			},
+			String: "(a -> b) -> c",
+		},
+		{
+			Name:  "negation",
+			Input: "!a & ~(b | c)",
+			Expected: expr.And{
+				expr.Not{Operand: expr.Var("a")},
+				expr.Not{Operand: expr.Or{expr.Var("b"), expr.Var("c")}},
+			},
+			String: "!a & !(b | c)",
+		},
+		{
+			Name:  "package-style identifiers",
+			Input: "etcd-operator.v0.9.4->etcd.v1/stable",
+			Expected: expr.Implies{
+				If:   expr.Var("etcd-operator.v0.9.4"),
+				Then: expr.Var("etcd.v1/stable"),
+			},
+			String: "etcd-operator.v0.9.4 -> etcd.v1/stable",
+		},
+		{
+			Name:     "quoted identifiers",
+			Input:    `"subject" | "a b" | "a->b"`,
+			Expected: expr.Or{expr.Var("subject"), expr.Var("a b"), expr.Var("a->b")},
+			String:   `"subject" | "a b" | "a->b"`,
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			n, err := expr.Parse(tt.Input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expected, n)
+			assert.Equal(t, tt.String, n.Format(expr.SubjectName))
+
+			roundTrip, err := expr.Parse(tt.String)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expected, roundTrip)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for input, message := range map[string]string{
+		"":         "unexpected end of expression",
+		"a &":      "unexpected end of expression",
+		"a b":      `unexpected "b" at offset 2`,
+		"(a | b":   "unexpected end of expression",
+		"a | )":    `unexpected ")" at offset 4`,
+		"a = b":    `unexpected "=" at offset 2`,
+		`"a`:       "unterminated identifier at offset 0",
+		`a | "\q"`: `invalid identifier "\q" at offset 4: invalid syntax`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := expr.Parse(input)
+			assert.EqualError(t, err, message)
+		})
+	}
+}
+
+func TestLit(t *testing.T) {
+	n, err := expr.Parse("subject -> (b & c) | d")
+	require.NoError(t, err)
+
+	lm := &testLitMapping{c: logic.NewC(), lits: map[deppy.Identifier]z.Lit{}}
+	m := n.Lit(lm, "a")
+	ids := []deppy.Identifier{"a", "b", "c", "d"}
+	for mask := 0; mask < 1<<len(ids); mask++ {
+		selected := map[deppy.Identifier]bool{}
+		vs := make([]bool, lm.c.Len())
+		for i, id := range ids {
+			selected[id] = mask&(1<<i) != 0
+			vs[lm.LitOf(id).Var()] = selected[id]
+		}
+		lm.c.Eval(vs)
+		expected := !selected["a"] || (selected["b"] && selected["c"]) || selected["d"]
+		assert.Equal(t, expected, vs[m.Var()] == m.IsPos(), "selected: %v", selected)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	n, err := expr.Parse("subject & !e -> (b & !c | subject) | (f -> d)")
+	require.NoError(t, err)
+	assert.Equal(t, []deppy.Identifier{"b", "d"}, n.Candidates(nil))
+}
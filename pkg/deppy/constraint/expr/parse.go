@@ -0,0 +1,236 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// Parse returns the expression described by s. The grammar, from
+// loosest to tightest binding, is:
+//
+//	a -> b    implication (right-associative)
+//	a | b     disjunction
+//	a & b     conjunction
+//	!a, ~a    negation
+//	(a)       grouping
+//
+// Operands are Identifiers, the keyword "subject", which refers to the
+// subject of the constraint, or the constants "true" and "false".
+// Identifiers may consist of letters, digits and any of "_.-+:/@";
+// any other Identifier, or one that collides with a keyword, must be
+// written as a double-quoted Go string literal.
+func Parse(s string) (Node, error) {
+	p := parser{input: s}
+	p.next()
+	n, err := p.parseImplies()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenKeyword
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenImplies
+	tokenOpen
+	tokenClose
+	tokenInvalid
+)
+
+var punctuation = map[byte]tokenKind{
+	'!': tokenNot,
+	'~': tokenNot,
+	'&': tokenAnd,
+	'|': tokenOr,
+	'(': tokenOpen,
+	')': tokenClose,
+}
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+type parser struct {
+	input  string
+	offset int
+	tok    token
+	err    error
+}
+
+func (p *parser) next() {
+	for p.offset < len(p.input) && strings.IndexByte(" \t\r\n", p.input[p.offset]) >= 0 {
+		p.offset++
+	}
+	start := p.offset
+	if start == len(p.input) {
+		p.tok = token{kind: tokenEOF, offset: start}
+		return
+	}
+
+	b := p.input[start]
+	kind, isPunctuation := punctuation[b]
+	switch {
+	case isPunctuation:
+		p.offset++
+		p.tok = token{kind: kind, text: p.input[start:p.offset], offset: start}
+	case strings.HasPrefix(p.input[start:], "->"):
+		p.offset += 2
+		p.tok = token{kind: tokenImplies, text: "->", offset: start}
+	case b == '"':
+		p.offset++
+		for p.offset < len(p.input) && p.input[p.offset] != '"' {
+			if p.input[p.offset] == '\\' {
+				p.offset++
+			}
+			p.offset++
+		}
+		if p.offset >= len(p.input) {
+			p.offset = len(p.input)
+			p.tok = token{kind: tokenInvalid, text: p.input[start:], offset: start}
+			p.err = fmt.Errorf("unterminated identifier at offset %d", start)
+			return
+		}
+		p.offset++
+		id, err := strconv.Unquote(p.input[start:p.offset])
+		if err != nil {
+			p.tok = token{kind: tokenInvalid, text: p.input[start:p.offset], offset: start}
+			p.err = fmt.Errorf("invalid identifier %s at offset %d: %w", p.input[start:p.offset], start, err)
+			return
+		}
+		p.tok = token{kind: tokenIdentifier, text: id, offset: start}
+	case isIdentifierByte(b):
+		for p.offset < len(p.input) && isIdentifierByte(p.input[p.offset]) && !strings.HasPrefix(p.input[p.offset:], "->") {
+			p.offset++
+		}
+		text := p.input[start:p.offset]
+		kind = tokenIdentifier
+		if isKeyword(text) {
+			kind = tokenKeyword
+		}
+		p.tok = token{kind: kind, text: text, offset: start}
+	default:
+		p.offset++
+		p.tok = token{kind: tokenInvalid, text: p.input[start:p.offset], offset: start}
+	}
+}
+
+func (p *parser) unexpected() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.tok.text, p.tok.offset)
+}
+
+func (p *parser) parseImplies() (Node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenImplies {
+		return n, nil
+	}
+	p.next()
+	then, err := p.parseImplies()
+	if err != nil {
+		return nil, err
+	}
+	return Implies{If: n, Then: then}, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenOr {
+		return n, nil
+	}
+	or := Or{n}
+	for p.tok.kind == tokenOr {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, n)
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenAnd {
+		return n, nil
+	}
+	and := And{n}
+	for p.tok.kind == tokenAnd {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, n)
+	}
+	return and, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	switch p.tok.kind {
+	case tokenNot:
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: n}, nil
+	case tokenOpen:
+		p.next()
+		n, err := p.parseImplies()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenClose {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return n, nil
+	case tokenIdentifier:
+		n := Var(deppy.Identifier(p.tok.text))
+		p.next()
+		return n, nil
+	case tokenKeyword:
+		var n Node
+		switch p.tok.text {
+		case SubjectName:
+			n = Subject{}
+		case "true":
+			n = And{}
+		case "false":
+			n = Or{}
+		}
+		p.next()
+		return n, nil
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,369 @@
+package constraint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// Decoder returns the Constraint encoded by the given JSON object,
+// which includes the "type" field of its envelope.
+type Decoder func(data []byte) (deppy.Constraint, error)
+
+var registry = struct {
+	sync.RWMutex
+	decoders map[string]Decoder
+	names    map[reflect.Type]string
+}{
+	decoders: map[string]Decoder{},
+	names:    map[reflect.Type]string{},
+}
+
+// Register associates a type name with the dynamic type of the given
+// prototype, so that Constraints of that type can be marshaled with
+// Marshal and unmarshaled with Unmarshal. Constraints are encoded as
+// a JSON object holding their encoding/json representation along
+// with a "type" field, for instance
+//
+//	{"type": "dependency", "dependencyIDs": ["a", "b"]}
+//
+// If decode is nil, objects of that type name are decoded with
+// UnmarshalStrict into a new value of the prototype's type, so that
+// fields the type does not have are rejected.
+func Register(name string, prototype deppy.Constraint, decode Decoder) error {
+	t := reflect.TypeOf(prototype)
+	if t == nil {
+		return errors.New("cannot register a nil constraint")
+	}
+	if decode == nil {
+		decode = decodeInto(t)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.decoders[name]; ok {
+		return fmt.Errorf("constraint type %q is already registered", name)
+	}
+	if other, ok := registry.names[t]; ok {
+		return fmt.Errorf("constraint type %s is already registered as %q", t, other)
+	}
+	registry.decoders[name] = decode
+	registry.names[t] = name
+	return nil
+}
+
+// MustRegister is like Register, but panics if the type cannot be
+// registered. It is intended for use in init functions.
+func MustRegister(name string, prototype deppy.Constraint, decode Decoder) {
+	if err := Register(name, prototype, decode); err != nil {
+		panic(err)
+	}
+}
+
+// decodeInto returns a Decoder that unmarshals objects into a new
+// value of the given type.
+func decodeInto(t reflect.Type) Decoder {
+	return func(data []byte) (deppy.Constraint, error) {
+		var v reflect.Value
+		if t.Kind() == reflect.Pointer {
+			v = reflect.New(t.Elem())
+		} else {
+			v = reflect.New(t)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		delete(fields, "type")
+		body, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		if err := UnmarshalStrict(body, v.Interface()); err != nil {
+			return nil, err
+		}
+		if t.Kind() != reflect.Pointer {
+			v = v.Elem()
+		}
+		return v.Interface().(deppy.Constraint), nil
+	}
+}
+
+// UnmarshalStrict is like json.Unmarshal, but rejects objects with
+// fields that the value does not have, including fields whose name
+// only differs in case, rather than ignoring them. The fields of a
+// value that implements json.Unmarshaler are left for it to check.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	if _, ok := v.(json.Unmarshaler); !ok {
+		if err := checkFieldNames(data, reflect.TypeOf(v)); err != nil {
+			return err
+		}
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// checkFieldNames returns an error if data is a JSON object with a
+// field that the given struct type does not have under that exact
+// name, which json.Decoder would match without regard to case.
+func checkFieldNames(data []byte, t reflect.Type) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// left for the decoder to report
+		return nil
+	}
+	names := map[string]struct{}{}
+	jsonFieldNames(t, names)
+	unknown := make([]string, 0, len(fields))
+	for name := range fields {
+		if _, ok := names[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("json: unknown field %q", unknown[0])
+}
+
+// jsonFieldNames adds the names under which encoding/json encodes the
+// fields of the given struct type to names.
+func jsonFieldNames(t reflect.Type, names map[string]struct{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				jsonFieldNames(ft, names)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = struct{}{}
+	}
+}
+
+// Marshal returns the JSON encoding of the given Constraint, whose
+// type must have been registered.
+func Marshal(c deppy.Constraint) ([]byte, error) {
+	registry.RLock()
+	name, ok := registry.names[reflect.TypeOf(c)]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("constraint type %T is not registered", c)
+	}
+
+	body, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 2 || body[0] != '{' {
+		return nil, fmt.Errorf("constraint type %q is not encoded as a JSON object", name)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["type"]; ok {
+		return nil, fmt.Errorf("constraint type %q has a field named \"type\"", name)
+	}
+
+	// Place the type first, for readability.
+	var b bytes.Buffer
+	b.WriteString(`{"type":`)
+	typ, _ := json.Marshal(name)
+	b.Write(typ)
+	if len(fields) > 0 {
+		b.WriteByte(',')
+		b.Write(body[1:])
+	} else {
+		b.WriteByte('}')
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal returns the Constraint encoded by the given JSON object,
+// which must have been produced by Marshal or follow the same format.
+func Unmarshal(data []byte) (deppy.Constraint, error) {
+	var envelope struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid constraint: %w", err)
+	}
+	if envelope.Type == nil {
+		return nil, errors.New("invalid constraint: missing type")
+	}
+
+	registry.RLock()
+	decode, ok := registry.decoders[*envelope.Type]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown constraint type %q", *envelope.Type)
+	}
+	c, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint of type %q: %w", *envelope.Type, err)
+	}
+	return c, nil
+}
+
+// Envelope wraps a Constraint so that it can be marshaled and
+// unmarshaled as a field of another type, in JSON or YAML, using the
+// format of Marshal.
+type Envelope struct {
+	deppy.Constraint
+}
+
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	return Marshal(e.Constraint)
+}
+
+func (e *Envelope) UnmarshalJSON(data []byte) error {
+	c, err := Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	e.Constraint = c
+	return nil
+}
+
+func (e Envelope) MarshalYAML() (interface{}, error) {
+	data, err := Marshal(e.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(data)
+}
+
+func (e *Envelope) UnmarshalYAML(node *yaml.Node) error {
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalJSON(data)
+}
+
+// jsonToYAML converts a JSON document into a YAML node, preserving
+// the order of object keys and the representation of numbers.
+func jsonToYAML(data []byte) (*yaml.Node, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return jsonValueToYAML(d)
+}
+
+func jsonValueToYAML(d *json.Decoder) (*yaml.Node, error) {
+	tok, err := d.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if tok == '{' {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+		}
+		for d.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			child, err := jsonValueToYAML(d)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		// Consume the closing delimiter.
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tok}, nil
+	case json.Number:
+		tag := "!!int"
+		if _, err := tok.Int64(); err != nil {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: tok.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(tok)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+func init() {
+	for name, prototype := range map[string]deppy.Constraint{
+		"mandatory":         &MandatoryConstraint{},
+		"optional":          &OptionalConstraint{},
+		"prohibited":        &ProhibitedConstraint{},
+		"dependency":        &DependencyConstraint{},
+		"recommends":        &RecommendsConstraint{},
+		"conflict":          &ConflictConstraint{},
+		"atMost":            &AtMostConstraint{},
+		"atLeast":           &AtLeastConstraint{},
+		"between":           &BetweenConstraint{},
+		"weightedAtMost":    &WeightedAtMostConstraint{},
+		"weightedAtLeast":   &WeightedAtLeastConstraint{},
+		"implies":           &ImpliesConstraint{},
+		"excludes":          &ExcludesConstraint{},
+		"mutuallyExclusive": &MutuallyExclusiveConstraint{},
+		"iff":               &IffConstraint{},
+		"or":                &OrConstraint{},
+		"clause":            &ClauseConstraint{},
+		"expr":              &ExprConstraint{},
+		"annotated":         &AnnotatedConstraint{},
+		"userFriendly":      &UserFriendlyConstraint{},
+	} {
+		MustRegister(name, prototype, nil)
+	}
+}
@@ -0,0 +1,225 @@
+package constraint_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-air/gini/z"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+// customConstraint is a stand-in for a Constraint implemented outside
+// of the constraint package.
+type customConstraint struct {
+	threshold int
+}
+
+func (c customConstraint) String(subject deppy.Identifier) string {
+	return fmt.Sprintf("%s has threshold %d", subject, c.threshold)
+}
+
+func (customConstraint) Apply(_ deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	return z.LitNull
+}
+
+func (customConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (customConstraint) Anchor() bool {
+	return false
+}
+
+func (c customConstraint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{"threshold": c.threshold})
+}
+
+func mustParseExpr(s string) deppy.Constraint {
+	c, err := constraint.ParseExpr(s)
+	Expect(err).NotTo(HaveOccurred())
+	return c
+}
+
+var _ = Describe("Registry", func() {
+	DescribeTable("built-in constraints round trip",
+		func(c deppy.Constraint) {
+			data, err := constraint.Marshal(c)
+			Expect(err).NotTo(HaveOccurred())
+			decoded, err := constraint.Unmarshal(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decoded).To(Equal(c))
+
+			data, err = yaml.Marshal(constraint.Envelope{Constraint: c})
+			Expect(err).NotTo(HaveOccurred())
+			var envelope constraint.Envelope
+			Expect(yaml.Unmarshal(data, &envelope)).To(Succeed())
+			Expect(envelope.Constraint).To(Equal(c))
+		},
+		Entry("mandatory", constraint.Mandatory()),
+		Entry("optional", constraint.Optional(3)),
+		Entry("prohibited", constraint.Prohibited()),
+		Entry("dependency", constraint.Dependency("a", "b")),
+		Entry("recommends", constraint.Recommends("a", "b")),
+		Entry("conflict", constraint.Conflict("a")),
+		Entry("at most", constraint.AtMost(1, "a", "b")),
+		Entry("at least", constraint.AtLeast(1, "a", "b")),
+		Entry("between", constraint.Between(1, 2, "a", "b", "c")),
+		Entry("weighted at most", constraint.WeightedAtMost(5, map[deppy.Identifier]int{"a": 2, "b": -3})),
+		Entry("weighted at least", constraint.WeightedAtLeast(5, map[deppy.Identifier]int{"a": 2, "b": 3})),
+		Entry("implies", constraint.Implies("a", "b")),
+		Entry("excludes", constraint.Excludes("a", "b")),
+		Entry("mutually exclusive", constraint.MutuallyExclusive("a", "b")),
+		Entry("iff", constraint.Iff("a")),
+		Entry("xor", constraint.Xor("a")),
+		Entry("or", constraint.Or("a", true, false)),
+		Entry("clause", constraint.Clause(constraint.Positive("a"), constraint.Negative("b"))),
+		Entry("expr", mustParseExpr(`subject & !"e 1" -> b & c | d`)),
+		Entry("annotated", constraint.WithMetadata(constraint.Dependency("a"), deppy.Metadata{
+			Origin: "manifest",
+			Labels: map[string]string{"bundle": "a.v1"},
+		})),
+	)
+
+	It("should encode the type name along with the fields", func() {
+		data, err := constraint.Marshal(constraint.Dependency("a", "b"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"type":"dependency","dependencyIDs":["a","b"]}`))
+
+		data, err = constraint.Marshal(constraint.Mandatory())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"type":"mandatory"}`))
+	})
+
+	It("should encode YAML with the type name first", func() {
+		data, err := yaml.Marshal(constraint.Envelope{Constraint: constraint.AtMost(1, "a", "b")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("type: atMost\nids:\n    - a\n    - b\nn: 1\n"))
+	})
+
+	It("should reject invalid input", func() {
+		_, err := constraint.Unmarshal([]byte(`{"dependencyIDs":["a"]}`))
+		Expect(err).To(MatchError("invalid constraint: missing type"))
+		_, err = constraint.Unmarshal([]byte(`{"type":"unknown"}`))
+		Expect(err).To(MatchError(`unknown constraint type "unknown"`))
+		_, err = constraint.Unmarshal([]byte(`{"type":"atMost","n":"one"}`))
+		Expect(err).To(MatchError(ContainSubstring(`invalid constraint of type "atMost"`)))
+		_, err = constraint.Unmarshal([]byte(`{"type":"expr","expression":"a &"}`))
+		Expect(err).To(MatchError(`invalid constraint of type "expr": invalid expression "a &": unexpected end of expression`))
+	})
+
+	It("should reject unknown fields in constraint bodies", func() {
+		_, err := constraint.Unmarshal([]byte(`{"type":"atMost","id":["a","b"],"n":1}`))
+		Expect(err).To(MatchError(`invalid constraint of type "atMost": json: unknown field "id"`))
+		_, err = constraint.Unmarshal([]byte(`{"type":"atMost","IDs":["a","b"],"n":1}`))
+		Expect(err).To(MatchError(`invalid constraint of type "atMost": json: unknown field "IDs"`))
+		_, err = constraint.Unmarshal([]byte(`{"type":"expr","expresion":"a"}`))
+		Expect(err).To(MatchError(`invalid constraint of type "expr": json: unknown field "expresion"`))
+		_, err = constraint.Unmarshal([]byte(`{"type":"annotated","constraint":{"type":"conflict","conflictID":"a"}}`))
+		Expect(err).To(MatchError(`invalid constraint of type "annotated": invalid constraint of type "conflict": json: unknown field "conflictID"`))
+
+		var envelope constraint.Envelope
+		err = yaml.Unmarshal([]byte("type: atMost\nid: [a, b]\nn: 1\n"), &envelope)
+		Expect(err).To(MatchError(`invalid constraint of type "atMost": json: unknown field "id"`))
+	})
+
+	It("should decode strictly", func() {
+		var v struct {
+			Name string `json:"name"`
+			Skip string `json:"-"`
+		}
+		Expect(constraint.UnmarshalStrict([]byte(`{"name":"a"}`), &v)).To(Succeed())
+		Expect(v.Name).To(Equal("a"))
+		Expect(constraint.UnmarshalStrict([]byte(`{"Name":"a"}`), &v)).To(MatchError(`json: unknown field "Name"`))
+		Expect(constraint.UnmarshalStrict([]byte(`{"Skip":"a"}`), &v)).To(MatchError(`json: unknown field "Skip"`))
+		Expect(constraint.UnmarshalStrict([]byte(`{"name":"a"} {}`), &v)).To(MatchError("unexpected data after JSON value"))
+	})
+
+	It("should round trip user friendly constraints with their message", func() {
+		c := constraint.NewUserFriendlyConstraint(constraint.Dependency("a"), func(_ deppy.Constraint, subject deppy.Identifier) string {
+			return fmt.Sprintf("%s needs a, and so does %s", subject, subject)
+		})
+		data, err := constraint.Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"type":"userFriendly","constraint":{"type":"dependency","dependencyIDs":["a"]},"message":"{subject} needs a, and so does {subject}"}`))
+
+		decoded, err := constraint.Unmarshal(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(BeAssignableToTypeOf(c))
+		Expect(decoded.(*constraint.UserFriendlyConstraint).Unwrap()).To(Equal(constraint.Dependency("a")))
+		Expect(decoded.String("x")).To(Equal("x needs a, and so does x"))
+		again, err := constraint.Marshal(decoded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal(data))
+	})
+
+	It("should round trip the constraints of domain variables", func() {
+		d := input.NewDomainVariable("x", []string{"1", "2", "3"})
+		equals, err := d.Equals("2")
+		Expect(err).NotTo(HaveOccurred())
+		lessThan, err := d.LessThan("3")
+		Expect(err).NotTo(HaveOccurred())
+		for _, c := range []deppy.Constraint{equals, lessThan, d.NotEqualsVariable(input.NewDomainVariable("y", []string{"1"}))} {
+			data, err := yaml.Marshal(constraint.Envelope{Constraint: c})
+			Expect(err).NotTo(HaveOccurred())
+			var envelope constraint.Envelope
+			Expect(yaml.Unmarshal(data, &envelope)).To(Succeed())
+			Expect(envelope.Constraint.String("x")).To(Equal(c.String("x")))
+			again, err := yaml.Marshal(envelope)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(again)).To(Equal(string(data)))
+		}
+	})
+
+	It("should refuse to marshal unregistered constraints", func() {
+		_, err := constraint.Marshal(customConstraint{threshold: 1})
+		Expect(err).To(MatchError("constraint type constraint_test.customConstraint is not registered"))
+	})
+
+	It("should refuse duplicate registrations", func() {
+		Expect(constraint.Register("mandatory", customConstraint{}, nil)).To(MatchError(`constraint type "mandatory" is already registered`))
+		Expect(constraint.Register("another", &constraint.MandatoryConstraint{}, nil)).To(MatchError(`constraint type *constraint.MandatoryConstraint is already registered as "mandatory"`))
+	})
+
+	It("should decode custom constraints with their decoder", func() {
+		Expect(constraint.Register("custom", customConstraint{}, func(data []byte) (deppy.Constraint, error) {
+			var fields struct {
+				Threshold int `json:"threshold"`
+			}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				return nil, err
+			}
+			return customConstraint{threshold: fields.Threshold}, nil
+		})).To(Succeed())
+
+		data, err := constraint.Marshal(customConstraint{threshold: 7})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"type":"custom","threshold":7}`))
+		decoded, err := constraint.Unmarshal(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(customConstraint{threshold: 7}))
+	})
+
+	It("should round trip simple variables", func() {
+		v := input.NewSimpleVariable("a", constraint.Mandatory(), constraint.Dependency("b", "c"))
+
+		data, err := json.Marshal(v)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"id":"a","constraints":[{"type":"mandatory"},{"type":"dependency","dependencyIDs":["b","c"]}]}`))
+		var fromJSON input.SimpleVariable
+		Expect(json.Unmarshal(data, &fromJSON)).To(Succeed())
+		Expect(&fromJSON).To(Equal(v))
+
+		data, err = yaml.Marshal(v)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("id: a\nconstraints:\n    - type: mandatory\n    - type: dependency\n      dependencyIDs:\n        - b\n        - c\n"))
+		var fromYAML input.SimpleVariable
+		Expect(yaml.Unmarshal(data, &fromYAML)).To(Succeed())
+		Expect(&fromYAML).To(Equal(v))
+	})
+})
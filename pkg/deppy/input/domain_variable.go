@@ -0,0 +1,234 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint/expr"
+)
+
+var _ deppy.Variable = &DomainVariable{}
+
+// DomainVariable is a Variable that, when selected, takes exactly one
+// value from a finite, ordered domain.
+//
+// The domain is encoded one-hot: every value is represented by a
+// boolean value Variable, returned by Variables, that can only be
+// selected together with the DomainVariable, and a selected
+// DomainVariable requires exactly one of its value Variables. Values
+// appearing earlier in the domain have higher preference than those
+// appearing later.
+type DomainVariable struct {
+	id          deppy.Identifier
+	values      []string
+	constraints []deppy.Constraint
+}
+
+func (d *DomainVariable) Identifier() deppy.Identifier {
+	return d.id
+}
+
+func (d *DomainVariable) Constraints() []deppy.Constraint {
+	ids := d.valueIdentifiers(func(string) bool { return true })
+	return append([]deppy.Constraint{
+		constraint.Dependency(ids...),
+		constraint.AtMost(1, ids...),
+	}, d.constraints...)
+}
+
+func (d *DomainVariable) AddConstraint(constraint deppy.Constraint) {
+	d.constraints = append(d.constraints, constraint)
+}
+
+// Values returns the domain of the Variable, in order.
+func (d *DomainVariable) Values() []string {
+	return d.values
+}
+
+// ValueIdentifier returns the Identifier of the value Variable that is
+// selected when the DomainVariable takes the given value.
+func (d *DomainVariable) ValueIdentifier(value string) deppy.Identifier {
+	return deppy.IdentifierFromString(fmt.Sprintf("%s=%s", d.id, value))
+}
+
+// Variables returns the DomainVariable followed by its value
+// Variables, all of which must be provided to the solver.
+func (d *DomainVariable) Variables() []deppy.Variable {
+	result := make([]deppy.Variable, 0, len(d.values)+1)
+	result = append(result, d)
+	for _, value := range d.values {
+		result = append(result, NewSimpleVariable(d.ValueIdentifier(value), constraint.Dependency(d.id)))
+	}
+	return result
+}
+
+// Value returns the value taken by the DomainVariable in the given
+// solution, or false if it was not selected.
+func (d *DomainVariable) Value(selected []deppy.Variable) (string, bool) {
+	ids := make(map[deppy.Identifier]struct{}, len(selected))
+	for _, variable := range selected {
+		ids[variable.Identifier()] = struct{}{}
+	}
+	return d.value(ids)
+}
+
+func (d *DomainVariable) value(selected map[deppy.Identifier]struct{}) (string, bool) {
+	for _, value := range d.values {
+		if _, ok := selected[d.ValueIdentifier(value)]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Equals returns a Constraint that permits the DomainVariable to be
+// selected only if it takes the given value, or an error if the value
+// is not in its domain.
+func (d *DomainVariable) Equals(value string) (deppy.Constraint, error) {
+	if err := d.checkValue(value); err != nil {
+		return nil, err
+	}
+	return d.is(fmt.Sprintf("equal %s", value), func(v string) bool { return v == value }), nil
+}
+
+// NotEquals returns a Constraint that permits the DomainVariable to be
+// selected only if it does not take the given value, or an error if
+// the value is not in its domain.
+func (d *DomainVariable) NotEquals(value string) (deppy.Constraint, error) {
+	if err := d.checkValue(value); err != nil {
+		return nil, err
+	}
+	return d.is(fmt.Sprintf("not equal %s", value), func(v string) bool { return v != value }), nil
+}
+
+// LessThan returns a Constraint that permits the DomainVariable to be
+// selected only if it takes a value that precedes the given value in
+// its domain, or an error if the value is not in its domain.
+func (d *DomainVariable) LessThan(value string) (deppy.Constraint, error) {
+	if err := d.checkValue(value); err != nil {
+		return nil, err
+	}
+	i := d.index(value)
+	return d.is(fmt.Sprintf("be less than %s", value), func(v string) bool { return d.index(v) < i }), nil
+}
+
+// EqualsVariable returns a Constraint that permits both DomainVariables
+// to take a value only if they take the same value.
+func (d *DomainVariable) EqualsVariable(other *DomainVariable) deppy.Constraint {
+	return d.relate(fmt.Sprintf("equal %s", other.id), other, func(v, w string) bool { return v == w })
+}
+
+// NotEqualsVariable returns a Constraint that forbids both
+// DomainVariables from taking the same value.
+func (d *DomainVariable) NotEqualsVariable(other *DomainVariable) deppy.Constraint {
+	return d.relate(fmt.Sprintf("not equal %s", other.id), other, func(v, w string) bool { return v != w })
+}
+
+// LessThanVariable returns a Constraint that permits the DomainVariable
+// to take a value only if the other DomainVariable takes a value that
+// follows it in the domain of the first. Values of the other
+// DomainVariable that are not in the domain of the first follow none
+// of its values.
+func (d *DomainVariable) LessThanVariable(other *DomainVariable) deppy.Constraint {
+	return d.relate(fmt.Sprintf("be less than %s", other.id), other, func(v, w string) bool {
+		j := d.index(w)
+		return j >= 0 && d.index(v) < j
+	})
+}
+
+// is returns a Constraint requiring a selected DomainVariable to take
+// one of the values accepted by the given predicate.
+func (d *DomainVariable) is(description string, accept func(value string) bool) deppy.Constraint {
+	return d.userFriendly(description, constraint.Expr(expr.Implies{
+		If:   expr.Var(d.id),
+		Then: d.any(accept),
+	}))
+}
+
+// relate returns a Constraint requiring every value taken by the
+// DomainVariable to be related to the value taken by the other by
+// the given predicate.
+func (d *DomainVariable) relate(description string, other *DomainVariable, related func(v, w string) bool) deppy.Constraint {
+	and := make(expr.And, 0, len(d.values))
+	for _, v := range d.values {
+		and = append(and, expr.Implies{
+			If:   expr.Var(d.ValueIdentifier(v)),
+			Then: other.any(func(w string) bool { return related(v, w) }),
+		})
+	}
+	return d.userFriendly(description, constraint.Expr(and))
+}
+
+// any returns an expression that holds when the DomainVariable takes
+// any of the values accepted by the given predicate.
+func (d *DomainVariable) any(accept func(value string) bool) expr.Or {
+	var or expr.Or
+	for _, id := range d.valueIdentifiers(accept) {
+		or = append(or, expr.Var(id))
+	}
+	return or
+}
+
+func (d *DomainVariable) valueIdentifiers(accept func(value string) bool) []deppy.Identifier {
+	var ids []deppy.Identifier
+	for _, value := range d.values {
+		if accept(value) {
+			ids = append(ids, d.ValueIdentifier(value))
+		}
+	}
+	return ids
+}
+
+func (d *DomainVariable) userFriendly(description string, c deppy.Constraint) deppy.Constraint {
+	return constraint.NewUserFriendlyConstraint(c, func(_ deppy.Constraint, _ deppy.Identifier) string {
+		return fmt.Sprintf("%s must %s", d.id, description)
+	})
+}
+
+// index returns the position of value in the domain, or -1 if the
+// value is not in the domain.
+func (d *DomainVariable) index(value string) int {
+	for i, each := range d.values {
+		if each == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkValue returns an error if the value is not in the domain.
+func (d *DomainVariable) checkValue(value string) error {
+	if d.index(value) < 0 {
+		return fmt.Errorf("value %q is not in the domain of %s", value, d.id)
+	}
+	return nil
+}
+
+// NewDomainVariable returns a DomainVariable with the given Identifier
+// and domain. The order of the values determines both the preference
+// between them and the ordering used by LessThan.
+func NewDomainVariable(id deppy.Identifier, values []string, constraints ...deppy.Constraint) *DomainVariable {
+	return &DomainVariable{
+		id:          id,
+		values:      values,
+		constraints: constraints,
+	}
+}
+
+// DomainValues decodes a solution into the values taken by each of the
+// given DomainVariables. DomainVariables that were not selected are
+// omitted.
+func DomainValues(selected []deppy.Variable, domains ...*DomainVariable) map[deppy.Identifier]string {
+	ids := make(map[deppy.Identifier]struct{}, len(selected))
+	for _, variable := range selected {
+		ids[variable.Identifier()] = struct{}{}
+	}
+	result := make(map[deppy.Identifier]string, len(domains))
+	for _, d := range domains {
+		if value, ok := d.value(ids); ok {
+			result[d.id] = value
+		}
+	}
+	return result
+}
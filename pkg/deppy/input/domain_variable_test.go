@@ -0,0 +1,76 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+func TestDomainVariableValueConstraints(t *testing.T) {
+	d := input.NewDomainVariable("x", []string{"1", "2", "3"})
+
+	type tc struct {
+		Name       string
+		Constraint func(value string) (deppy.Constraint, error)
+		Message    string
+	}
+
+	for _, tt := range []tc{
+		{
+			Name:       "equals",
+			Constraint: d.Equals,
+			Message:    "x must equal 2",
+		},
+		{
+			Name:       "not equals",
+			Constraint: d.NotEquals,
+			Message:    "x must not equal 2",
+		},
+		{
+			Name:       "less than",
+			Constraint: d.LessThan,
+			Message:    "x must be less than 2",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			c, err := tt.Constraint("2")
+			require.NoError(t, err)
+			assert.Equal(t, tt.Message, c.String("x"))
+
+			c, err = tt.Constraint("4")
+			assert.EqualError(t, err, `value "4" is not in the domain of x`)
+			assert.Nil(t, c)
+		})
+	}
+}
+
+func TestDomainVariableVariables(t *testing.T) {
+	d := input.NewDomainVariable("x", []string{"1", "2"})
+
+	var ids []deppy.Identifier
+	for _, v := range d.Variables() {
+		ids = append(ids, v.Identifier())
+	}
+	assert.Equal(t, []deppy.Identifier{"x", "x=1", "x=2"}, ids)
+	assert.Equal(t, []string{"x requires at least one of x=1, x=2", "x permits at most 1 of x=1, x=2"}, []string{
+		d.Constraints()[0].String("x"),
+		d.Constraints()[1].String("x"),
+	})
+}
+
+func TestDomainValues(t *testing.T) {
+	x := input.NewDomainVariable("x", []string{"1", "2"})
+	y := input.NewDomainVariable("y", []string{"1", "2"})
+	selected := []deppy.Variable{x, input.NewSimpleVariable(x.ValueIdentifier("2"))}
+
+	value, ok := x.Value(selected)
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+	_, ok = y.Value(selected)
+	assert.False(t, ok)
+	assert.Equal(t, map[deppy.Identifier]string{"x": "2"}, input.DomainValues(selected, x, y))
+}
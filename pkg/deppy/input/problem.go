@@ -0,0 +1,109 @@
+package input
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+)
+
+// ProblemSchema is the JSON Schema of the problem files read by
+// LoadProblem.
+//
+//go:embed problem.schema.json
+var ProblemSchema []byte
+
+// problemEncoding is the JSON and YAML representation of a
+// deppy.Problem. Variables are encoded as SimpleVariables, and
+// constraints with constraint.Marshal.
+type problemEncoding struct {
+	Variables   []*SimpleVariable     `json:"variables" yaml:"variables"`
+	Constraints []constraint.Envelope `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+}
+
+// LoadProblem reads a Problem from a YAML or JSON document, for
+// instance
+//
+//	variables:
+//	  - id: a
+//	    constraints:
+//	      - type: mandatory
+//	      - type: dependency
+//	        dependencyIDs: [b, c]
+//	  - id: b
+//	  - id: c
+//	constraints:
+//	  - type: atMost
+//	    ids: [b, c]
+//	    n: 1
+//
+// Every constraint names its type, as registered with
+// constraint.Register, along with the fields of that type. Fields
+// that are not part of the format are rejected at every level,
+// including within constraints, so that typos are not silently
+// ignored. ProblemSchema describes the same format for editors and
+// other tools, but is not itself checked.
+func LoadProblem(r io.Reader) (deppy.Problem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return deppy.Problem{}, err
+	}
+
+	var encoding problemEncoding
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = constraint.UnmarshalStrict(data, &encoding)
+	} else {
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		err = d.Decode(&encoding)
+		if errors.Is(err, io.EOF) {
+			err = errors.New("empty document")
+		}
+	}
+	if err != nil {
+		return deppy.Problem{}, fmt.Errorf("invalid problem: %w", err)
+	}
+	return encoding.problem()
+}
+
+// LoadProblemFile reads a Problem from the YAML or JSON file at the
+// given path, as described by LoadProblem.
+func LoadProblemFile(path string) (deppy.Problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return deppy.Problem{}, err
+	}
+	defer f.Close()
+
+	problem, err := LoadProblem(f)
+	if err != nil {
+		return deppy.Problem{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return problem, nil
+}
+
+func (e problemEncoding) problem() (deppy.Problem, error) {
+	var problem deppy.Problem
+	seen := make(map[deppy.Identifier]struct{}, len(e.Variables))
+	for i, variable := range e.Variables {
+		if variable == nil || variable.Identifier() == "" {
+			return deppy.Problem{}, fmt.Errorf("invalid problem: variable %d has no id", i)
+		}
+		if _, ok := seen[variable.Identifier()]; ok {
+			return deppy.Problem{}, fmt.Errorf("invalid problem: duplicate variable %q", variable.Identifier())
+		}
+		seen[variable.Identifier()] = struct{}{}
+		problem.Variables = append(problem.Variables, variable)
+	}
+	for _, c := range e.Constraints {
+		problem.Constraints = append(problem.Constraints, c.Constraint)
+	}
+	return problem, nil
+}
@@ -1,7 +1,12 @@
 package input
 
 import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+
 	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
 )
 
 var _ deppy.Variable = &SimpleVariable{}
@@ -29,3 +34,58 @@ func NewSimpleVariable(id deppy.Identifier, constraints ...deppy.Constraint) *Si
 		constraints: constraints,
 	}
 }
+
+// simpleVariableEncoding is the JSON and YAML representation of a
+// SimpleVariable. Constraints are encoded with constraint.Marshal, so
+// their types must be registered.
+type simpleVariableEncoding struct {
+	ID          deppy.Identifier      `json:"id" yaml:"id"`
+	Constraints []constraint.Envelope `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+}
+
+func (s *SimpleVariable) encoding() simpleVariableEncoding {
+	encoding := simpleVariableEncoding{ID: s.id}
+	for _, c := range s.constraints {
+		encoding.Constraints = append(encoding.Constraints, constraint.Envelope{Constraint: c})
+	}
+	return encoding
+}
+
+func (s *SimpleVariable) decode(encoding simpleVariableEncoding) {
+	s.id = encoding.ID
+	s.constraints = nil
+	for _, c := range encoding.Constraints {
+		s.constraints = append(s.constraints, c.Constraint)
+	}
+}
+
+func (s *SimpleVariable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.encoding())
+}
+
+func (s *SimpleVariable) UnmarshalJSON(data []byte) error {
+	var encoding simpleVariableEncoding
+	if err := constraint.UnmarshalStrict(data, &encoding); err != nil {
+		return err
+	}
+	s.decode(encoding)
+	return nil
+}
+
+func (s *SimpleVariable) MarshalYAML() (interface{}, error) {
+	return s.encoding(), nil
+}
+
+// UnmarshalYAML decodes the variable through its JSON representation,
+// since yaml.Node.Decode does not reject unknown fields.
+func (s *SimpleVariable) UnmarshalYAML(node *yaml.Node) error {
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalJSON(data)
+}
@@ -0,0 +1,34 @@
+package input
+
+import (
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+var _ deppy.VariableSource = &LazyVariableSource{}
+
+// LookupFunc returns the Variable with the given Identifier, or false
+// if no such Variable exists.
+type LookupFunc func(id deppy.Identifier) (deppy.Variable, bool, error)
+
+// LazyVariableSource is a deppy.VariableSource that starts from a fixed
+// set of Variables and resolves every other Variable through a
+// LookupFunc, for instance by querying a catalog.
+type LazyVariableSource struct {
+	initial []deppy.Variable
+	lookup  LookupFunc
+}
+
+func (s *LazyVariableSource) Initial() ([]deppy.Variable, error) {
+	return s.initial, nil
+}
+
+func (s *LazyVariableSource) Lookup(id deppy.Identifier) (deppy.Variable, bool, error) {
+	return s.lookup(id)
+}
+
+func NewLazyVariableSource(lookup LookupFunc, initial ...deppy.Variable) *LazyVariableSource {
+	return &LazyVariableSource{
+		initial: initial,
+		lookup:  lookup,
+	}
+}
@@ -78,3 +78,88 @@ func BenchmarkSolve(b *testing.B) {
 		}
 	}
 }
+
+// scaledBenchmarkInput returns a catalog-shaped problem of the given
+// size. Dependencies and conflicts only ever point at Variables with
+// a higher index, so the dependency graph is acyclic and the problem
+// stays satisfiable regardless of its size.
+func scaledBenchmarkInput(length int) []deppy.Variable {
+	const (
+		seed        = 9
+		pMandatory  = .001
+		pDependency = .15
+		nDependency = 6
+		pConflict   = .05
+		nConflict   = 3
+	)
+
+	random := rand.New(rand.NewSource(seed)) //nolint:gosec // G404: Use of weak random number generator (math/rand instead of crypto/rand) is ignored as this is not security-sensitive.
+
+	id := func(i int) deppy.Identifier {
+		return deppy.Identifier(strconv.Itoa(i))
+	}
+
+	later := func(i int) int {
+		return i + 1 + random.Intn(length-i-1)
+	}
+
+	result := make([]deppy.Variable, length)
+	for i := range result {
+		var c []deppy.Constraint
+		if random.Float64() < pMandatory {
+			c = append(c, constraint.Mandatory())
+		}
+		if i < length-1 && random.Float64() < pDependency {
+			d := make([]deppy.Identifier, random.Intn(nDependency-1)+1)
+			for x := range d {
+				d[x] = id(later(i))
+			}
+			c = append(c, constraint.Dependency(d...))
+		}
+		if i < length-1 && random.Float64() < pConflict {
+			n := random.Intn(nConflict-1) + 1
+			for x := 0; x < n; x++ {
+				c = append(c, constraint.Conflict(id(later(i))))
+			}
+		}
+		result[i] = TestVariable{
+			identifier:  id(i),
+			constraints: c,
+		}
+	}
+	return result
+}
+
+func BenchmarkSolveScale(b *testing.B) {
+	for _, length := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(strconv.Itoa(length), func(b *testing.B) {
+			input := scaledBenchmarkInput(length)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s, err := New()
+				if err != nil {
+					b.Fatalf("failed to initialize solver: %s", err)
+				}
+				if _, err := s.Solve(input); err != nil {
+					b.Fatalf("failed to solve: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNewLitMappingScale(b *testing.B) {
+	for _, length := range []int{10_000, 100_000, 1_000_000} {
+		b.Run(strconv.Itoa(length), func(b *testing.B) {
+			input := scaledBenchmarkInput(length)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := newLitMapping(input); err != nil {
+					b.Fatalf("failed to initialize lit mapping: %s", err)
+				}
+			}
+		})
+	}
+}
@@ -35,6 +35,24 @@ func TestOrder(t *testing.T) {
 			Name:       "conflict",
 			Constraint: constraint.Conflict("a"),
 		},
+		{
+			Name:       "implies",
+			Constraint: constraint.Implies("a", "b"),
+			Expected:   []deppy.Identifier{"a", "b"},
+		},
+		{
+			Name:       "excludes",
+			Constraint: constraint.Excludes("a", "b"),
+		},
+		{
+			Name:       "iff",
+			Constraint: constraint.Iff("a"),
+			Expected:   []deppy.Identifier{"a"},
+		},
+		{
+			Name:       "xor",
+			Constraint: constraint.Xor("a"),
+		},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			assert.Equal(t, tt.Expected, tt.Constraint.Order())
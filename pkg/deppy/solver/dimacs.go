@@ -0,0 +1,86 @@
+package solver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-air/gini/z"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// cnf is an inter.Adder that records the clauses added to it.
+type cnf struct {
+	clauses [][]z.Lit
+	clause  []z.Lit
+}
+
+func (c *cnf) Add(m z.Lit) {
+	if m == z.LitNull {
+		c.clauses = append(c.clauses, c.clause)
+		c.clause = nil
+		return
+	}
+	c.clause = append(c.clause, m)
+}
+
+// WriteDIMACS writes the CNF formula that the solver would build for
+// the given Problem to w, in DIMACS format. Every constraint is
+// encoded by a literal of the formula that holds if and only if the
+// constraint is satisfied; the literal of each hard constraint is
+// asserted by a unit clause, so that the formula is satisfiable if and
+// only if the hard constraints can be satisfied together. Soft
+// constraints, such as Optional anchors, are not asserted.
+//
+// Comment lines preceding the header map the number of each DIMACS
+// variable to the Identifier of the Variable it represents, and the
+// literal of each constraint to the AppliedConstraint it encodes, for
+// instance
+//
+//	c variable 2 a
+//	c constraint 2 a is mandatory
+//	c soft constraint 3 b is requested with priority 1
+//
+// Other DIMACS variables are internal to the encoding.
+func WriteDIMACS(w io.Writer, problem deppy.Problem) error {
+	litMap, err := newLitMapping(problem.Variables, problem.Constraints...)
+	if err != nil {
+		return err
+	}
+	var formula cnf
+	litMap.c.ToCnf(&formula)
+	if err := litMap.Error(); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c deppy encoding of %d variables and %d constraints\n", len(litMap.inorder), len(litMap.applied))
+	for i, variable := range litMap.inorder {
+		fmt.Fprintf(bw, "c variable %d %s\n", litMap.varLits[i].Dimacs(), comment(variable.Identifier().String()))
+	}
+	for i, applied := range litMap.applied {
+		m := litMap.constraintsInOrder[i]
+		if litMap.disabled[i] {
+			fmt.Fprintf(bw, "c soft constraint %d %s\n", m.Dimacs(), comment(applied.String()))
+			continue
+		}
+		fmt.Fprintf(bw, "c constraint %d %s\n", m.Dimacs(), comment(applied.String()))
+		formula.clauses = append(formula.clauses, []z.Lit{m})
+	}
+
+	fmt.Fprintf(bw, "p cnf %d %d\n", litMap.c.Len()-1, len(formula.clauses))
+	for _, clause := range formula.clauses {
+		for _, m := range clause {
+			fmt.Fprintf(bw, "%d ", m.Dimacs())
+		}
+		fmt.Fprintln(bw, "0")
+	}
+	return bw.Flush()
+}
+
+// comment keeps text that is written to a comment line on that line.
+func comment(text string) string {
+	return strings.ReplaceAll(text, "\n", " ")
+}
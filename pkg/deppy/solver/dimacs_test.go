@@ -0,0 +1,97 @@
+package solver
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-air/gini"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+)
+
+func TestWriteDIMACS(t *testing.T) {
+	type tc struct {
+		Name        string
+		Problem     deppy.Problem
+		Satisfiable bool
+		Comments    []string
+	}
+
+	for _, tt := range []tc{
+		{
+			Name: "satisfiable",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Mandatory(), constraint.Dependency("b", "c")),
+					variable("b", constraint.Optional(1)),
+					variable("c"),
+				},
+				Constraints: []deppy.Constraint{
+					constraint.AtMost(1, "b", "c"),
+				},
+			},
+			Satisfiable: true,
+			Comments: []string{
+				"c deppy encoding of 3 variables and 4 constraints",
+				"c variable 2 a",
+				"c variable 3 b",
+				"c variable 4 c",
+				"c constraint 2 a is mandatory",
+				"c soft constraint 3 b is requested with priority 1",
+			},
+		},
+		{
+			Name: "unsatisfiable",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Mandatory(), constraint.Dependency("b")),
+					variable("b", constraint.Prohibited()),
+				},
+			},
+			Comments: []string{
+				"c variable 2 a",
+				"c constraint -3 b is ProhibitedConstraint",
+			},
+		},
+		{
+			Name: "soft constraints are not asserted",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Optional(1), constraint.Dependency("b")),
+					variable("b", constraint.Prohibited()),
+				},
+			},
+			Satisfiable: true,
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b bytes.Buffer
+			require.NoError(t, WriteDIMACS(&b, tt.Problem))
+
+			lines := strings.Split(b.String(), "\n")
+			for _, c := range tt.Comments {
+				assert.Contains(t, lines, c)
+			}
+
+			g, err := gini.NewDimacs(&b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Satisfiable, g.Solve() == satisfiable)
+
+			s, err := New()
+			require.NoError(t, err)
+			_, err = s.SolveProblem(tt.Problem)
+			assert.Equal(t, tt.Satisfiable, err == nil)
+		})
+	}
+}
+
+func TestWriteDIMACSError(t *testing.T) {
+	err := WriteDIMACS(&bytes.Buffer{}, deppy.Problem{
+		Variables: []deppy.Variable{variable("a"), variable("a")},
+	})
+	assert.Equal(t, DuplicateIdentifier("a"), err)
+}
@@ -0,0 +1,105 @@
+package solver
+
+import (
+	"github.com/go-air/gini"
+	"github.com/go-air/gini/inter"
+	"github.com/go-air/gini/z"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// Incremental solves a sequence of related problems over the same
+// Variables with a single SAT solver instance, so that what is
+// learned while solving one problem speeds up the next. Global
+// constraints accumulate across calls to AddConstraints, while the
+// assumptions passed to Solve only hold for that call.
+//
+// Unlike Solve, Incremental only decides satisfiability: the
+// Variables it returns are those of the first solution found, without
+// taking preferences into account or minimizing the selection, and
+// soft constraints are ignored.
+type Incremental struct {
+	litMap *litMapping
+	g      inter.S
+	// marks records the circuit nodes already added to g.
+	marks []int8
+	// hard holds the literals of the constraints that are assumed
+	// by every call to Solve.
+	hard []z.Lit
+}
+
+// Incremental returns an Incremental solver for the given Variables
+// and global constraints.
+func (s *Solver) Incremental(variables []deppy.Variable, globals ...deppy.Constraint) (*Incremental, error) {
+	litMap, err := newLitMapping(variables, globals...)
+	if err != nil {
+		return nil, err
+	}
+	if err := litMap.Error(); err != nil {
+		return nil, err
+	}
+	inc := &Incremental{litMap: litMap, g: gini.New()}
+	for i, m := range litMap.constraintsInOrder {
+		if !litMap.disabled[i] {
+			inc.hard = append(inc.hard, m)
+		}
+	}
+	inc.addClauses(inc.hard)
+	return inc, nil
+}
+
+// AddConstraints adds global constraints that hold in every
+// subsequent call to Solve.
+func (inc *Incremental) AddConstraints(globals ...deppy.Constraint) error {
+	ms, err := inc.apply(globals)
+	if err != nil {
+		return err
+	}
+	inc.hard = append(inc.hard, ms...)
+	return nil
+}
+
+// Solve returns the Variables selected in a solution that satisfies
+// the accumulated constraints along with the given assumptions, which
+// are global constraints that only hold for this call. If there is no
+// such solution, a NotSatisfiable error is returned.
+func (inc *Incremental) Solve(assumptions ...deppy.Constraint) ([]deppy.Variable, error) {
+	start := len(inc.litMap.constraintsInOrder)
+	ms, err := inc.apply(assumptions)
+	if err != nil {
+		return nil, err
+	}
+	// forget the assumptions once solved, so that they can neither
+	// accumulate nor appear in the conflicts of a later call
+	defer inc.litMap.truncate(start)
+	inc.g.Assume(inc.hard...)
+	inc.g.Assume(ms...)
+	switch inc.g.Solve() {
+	case satisfiable:
+		return inc.litMap.Variables(inc.g), nil
+	default:
+		return nil, deppy.NotSatisfiable(inc.litMap.Conflicts(inc.g))
+	}
+}
+
+// apply translates the given global constraints and teaches the
+// solver the clauses that encode them, returning the literals of
+// those that are not soft constraints.
+func (inc *Incremental) apply(globals []deppy.Constraint) ([]z.Lit, error) {
+	start := len(inc.litMap.constraintsInOrder)
+	if err := inc.litMap.extend(globals...); err != nil {
+		return nil, err
+	}
+	var ms []z.Lit
+	for i := start; i < len(inc.litMap.constraintsInOrder); i++ {
+		if !inc.litMap.disabled[i] {
+			ms = append(ms, inc.litMap.constraintsInOrder[i])
+		}
+	}
+	inc.addClauses(ms)
+	return ms, nil
+}
+
+func (inc *Incremental) addClauses(roots []z.Lit) {
+	inc.marks, _ = inc.litMap.c.CnfSince(inc.g, inc.marks, roots...)
+}
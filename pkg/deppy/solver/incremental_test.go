@@ -0,0 +1,80 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+)
+
+func TestIncremental(t *testing.T) {
+	s, err := New()
+	require.NoError(t, err)
+	inc, err := s.Incremental([]deppy.Variable{
+		variable("a", constraint.Dependency("b", "c")),
+		variable("b"),
+		variable("c"),
+	}, constraint.AtMost(1, "b", "c"))
+	require.NoError(t, err)
+
+	ids := func(selection []deppy.Variable) []deppy.Identifier {
+		var result []deppy.Identifier
+		for _, v := range selection {
+			result = append(result, v.Identifier())
+		}
+		return result
+	}
+
+	// assumptions only hold for a single call
+	selection, err := inc.Solve(constraint.Clause(constraint.Positive("a")), constraint.Clause(constraint.Negative("b")))
+	require.NoError(t, err)
+	assert.Equal(t, []deppy.Identifier{"a", "c"}, ids(selection))
+
+	selection, err = inc.Solve(constraint.Clause(constraint.Positive("b")))
+	require.NoError(t, err)
+	assert.Contains(t, ids(selection), deppy.Identifier("b"))
+	assert.NotContains(t, ids(selection), deppy.Identifier("c"))
+
+	// constraints accumulate
+	require.NoError(t, inc.AddConstraints(constraint.Clause(constraint.Negative("c"))))
+	notB := constraint.Clause(constraint.Negative("b"))
+	_, err = inc.Solve(constraint.Clause(constraint.Positive("a")), notB)
+	var unsat deppy.NotSatisfiable
+	require.ErrorAs(t, err, &unsat)
+	assert.Contains(t, unsat, deppy.AppliedConstraint{Constraint: notB})
+
+	selection, err = inc.Solve(constraint.Clause(constraint.Positive("a")))
+	require.NoError(t, err)
+	assert.Equal(t, []deppy.Identifier{"a", "b"}, ids(selection))
+
+	// constraints that cannot be applied are discarded
+	_, err = inc.Solve(constraint.Clause(constraint.Positive("x")))
+	assert.EqualError(t, err, `1 errors encountered: variable "x" referenced but not provided`)
+	_, err = inc.Solve()
+	assert.NoError(t, err)
+}
+
+func TestIncrementalForgetsAssumptions(t *testing.T) {
+	s, err := New()
+	require.NoError(t, err)
+	x := constraint.Clause(constraint.Positive("x"))
+	inc, err := s.Incremental([]deppy.Variable{variable("x")}, x)
+	require.NoError(t, err)
+	n := len(inc.litMap.constraintsInOrder)
+
+	// the assumption shares its literal with the global constraint
+	assumed := constraint.WithMetadata(constraint.Clause(constraint.Positive("x")), deppy.Metadata{Origin: "first call"})
+	_, err = inc.Solve(assumed)
+	require.NoError(t, err)
+	assert.Len(t, inc.litMap.constraintsInOrder, n)
+
+	notX := constraint.Clause(constraint.Negative("x"))
+	_, err = inc.Solve(notX)
+	var unsat deppy.NotSatisfiable
+	require.ErrorAs(t, err, &unsat)
+	assert.ElementsMatch(t, deppy.NotSatisfiable{{Constraint: x}, {Constraint: notX}}, unsat)
+	assert.Len(t, inc.litMap.constraintsInOrder, n)
+}
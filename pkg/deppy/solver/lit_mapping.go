@@ -2,6 +2,7 @@ package solver
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/go-air/gini/inter"
@@ -26,60 +27,295 @@ func (inconsistentLitMapping) Error() string {
 // litMapping performs translation between the input and output types of
 // Solve (Constraints, Variables, etc.) and the variables that
 // appear in the SAT formula.
+//
+// Identifiers are interned to dense indices into inorder, and all
+// translation tables keyed by literal are slices indexed by z.Lit or
+// z.Var, so that the cost of the mapping stays proportional to the
+// size of the input rather than to the number of map entries.
 type litMapping struct {
-	inorder            []deppy.Variable
-	variables          map[z.Lit]deppy.Variable
-	lits               map[deppy.Identifier]z.Lit
-	constraints        map[z.Lit]deppy.AppliedConstraint
+	inorder []deppy.Variable
+	// ids interns each Identifier to its index in inorder.
+	ids map[deppy.Identifier]int32
+	// varLits holds the positive literal of inorder[i].
+	varLits []z.Lit
+	// litVars holds, for each z.Var, one more than the index in
+	// inorder of the Variable it represents, or 0 if none.
+	litVars []int32
+	// applied holds every applied constraint in input order, and
+	// constraintsInOrder the literal that each one was mapped to.
+	applied            []deppy.AppliedConstraint
 	constraintsInOrder []z.Lit
-	c                  *logic.C
-	errs               inconsistentLitMapping
+	// litConstraints holds, for each z.Lit, one more than the index
+	// in applied of the constraint it represents, or 0 if none.
+	litConstraints []int32
+	// softs holds the index in applied of every soft constraint,
+	// and disabled whether the constraint at each index in applied
+	// is currently excluded from the assumptions.
+	softs    []int32
+	disabled []bool
+	// anchors holds every application of an anchor constraint, in
+	// input order.
+	anchors []anchor
+	// preferences holds the choices expressed by the Order of each
+	// global constraint, in input order.
+	preferences [][]deppy.Identifier
+	// source provides Variables that are referenced but have not
+	// been loaded yet. It is only consulted while the mapping is
+	// being built.
+	source    deppy.VariableSource
+	sourceErr error
+	// global is set while a global constraint is applied, and
+	// subjectRead records whether it looked up its (empty) subject.
+	global      bool
+	subjectRead bool
+	c           *logic.C
+	errs        inconsistentLitMapping
+}
+
+// anchor records the index in inorder of a Variable with an anchor
+// constraint, and the index in applied of that constraint, or -1 if it
+// was not applied.
+type anchor struct {
+	variable int32
+	applied  int32
+}
+
+// sliceSource is a deppy.VariableSource that provides a fixed slice
+// of Variables up front and nothing else.
+type sliceSource []deppy.Variable
+
+func (s sliceSource) Initial() ([]deppy.Variable, error) {
+	return s, nil
+}
+
+func (sliceSource) Lookup(_ deppy.Identifier) (deppy.Variable, bool, error) {
+	return nil, false, nil
 }
 
 // newLitMapping returns a new litMapping with its state initialized based on
-// the provided slice of Variables. This includes construction of
-// the translation tables between Variables/Constraints and the
-// inputs to the underlying solver.
-func newLitMapping(variables []deppy.Variable) (*litMapping, error) {
+// the provided slice of Variables and global constraints. This
+// includes construction of the translation tables between
+// Variables/Constraints and the inputs to the underlying solver.
+func newLitMapping(variables []deppy.Variable, globals ...deppy.Constraint) (*litMapping, error) {
+	return newLitMappingFromSource(sliceSource(variables), globals, len(variables))
+}
+
+// newLitMappingFromSource returns a new litMapping containing the
+// initial Variables of the provided source and every Variable
+// transitively referenced by their constraints or by the given global
+// constraints, either through Apply or through Order. Referenced
+// Variables are requested from the source as they are discovered, so
+// the remainder of the source is never loaded.
+func newLitMappingFromSource(source deppy.VariableSource, globals []deppy.Constraint, capHint int) (*litMapping, error) {
 	d := litMapping{
-		inorder:     variables,
-		variables:   make(map[z.Lit]deppy.Variable, len(variables)),
-		lits:        make(map[deppy.Identifier]z.Lit, len(variables)),
-		constraints: make(map[z.Lit]deppy.AppliedConstraint),
-		c:           logic.NewCCap(len(variables)),
+		ids:     make(map[deppy.Identifier]int32, capHint),
+		inorder: make([]deppy.Variable, 0, capHint),
+		varLits: make([]z.Lit, 0, capHint),
+		c:       logic.NewCCap(capHint),
+		source:  source,
 	}
-
-	// First pass to assign lits:
-	for _, variable := range variables {
-		im := d.c.Lit()
-		if _, ok := d.lits[variable.Identifier()]; ok {
+	initial, err := source.Initial()
+	if err != nil {
+		return nil, err
+	}
+	for _, variable := range initial {
+		if _, ok := d.ids[variable.Identifier()]; ok {
 			return nil, DuplicateIdentifier(variable.Identifier())
 		}
-		d.lits[variable.Identifier()] = im
-		d.variables[im] = variable
+		d.add(variable)
 	}
 
-	for _, variable := range variables {
-		for _, constraint := range variable.Constraints() {
-			m := constraint.Apply(&d, variable.Identifier())
-			if m == z.LitNull {
-				// This constraint doesn't have a
-				// useful representation in the SAT
-				// inputs.
-				continue
-			}
+	// Global constraints have no subject, and are applied before
+	// the constraints of any Variable.
+	for _, constraint := range globals {
+		if err := d.apply(nil, constraint); err != nil {
+			return nil, err
+		}
+		if cc, ok := constraint.(deppy.ChoiceConstraint); ok {
+			d.preferences = append(d.preferences, cc.Choices()...)
+		} else if ids := constraint.Order(); len(ids) > 0 {
+			d.preferences = append(d.preferences, ids)
+		}
+	}
 
-			d.constraints[m] = deppy.AppliedConstraint{
-				Variable:   variable,
-				Constraint: constraint,
+	// Applying the constraints of a Variable may load further
+	// Variables, which are appended to inorder and visited in
+	// turn.
+	for i := 0; i < len(d.inorder); i++ {
+		variable := d.inorder[i]
+		for _, constraint := range variable.Constraints() {
+			if err := d.apply(variable, constraint); err != nil {
+				return nil, err
 			}
-			d.constraintsInOrder = append(d.constraintsInOrder, m)
 		}
 	}
 
+	// Every literal in the circuit is now known, so the reverse
+	// tables can be allocated once at their final size, and no
+	// further Variables may be loaded.
+	d.source = nil
+	d.litVars = make([]int32, d.c.Len())
+	for i, m := range d.varLits {
+		d.litVars[m.Var()] = int32(i) + 1
+	}
+	d.litConstraints = make([]int32, 2*d.c.Len())
+	for i, m := range d.constraintsInOrder {
+		d.litConstraints[m] = int32(i) + 1
+	}
+
 	return &d, nil
 }
 
+// extend applies further global constraints once the mapping has been
+// built, growing the reverse tables to cover the new literals. The
+// constraints may only refer to Variables that are already loaded;
+// if any of them cannot be applied, none of them is kept.
+func (d *litMapping) extend(globals ...deppy.Constraint) error {
+	for _, constraint := range globals {
+		if err := deppy.Validate(constraint); err != nil {
+			return fmt.Errorf("invalid global constraint: %w", err)
+		}
+	}
+	start, softs, errs := len(d.constraintsInOrder), len(d.softs), len(d.errs)
+	var err error
+	for _, constraint := range globals {
+		if err = d.apply(nil, constraint); err != nil {
+			break
+		}
+	}
+	if err == nil && len(d.errs) > errs {
+		err = aggregate(d.errs[errs:])
+	}
+	if err != nil {
+		d.errs = d.errs[:errs]
+		d.applied = d.applied[:start]
+		d.constraintsInOrder = d.constraintsInOrder[:start]
+		d.disabled = d.disabled[:start]
+		d.softs = d.softs[:softs]
+		return err
+	}
+	if n := d.c.Len(); n > len(d.litVars) {
+		d.litVars = append(d.litVars, make([]int32, n-len(d.litVars))...)
+		d.litConstraints = append(d.litConstraints, make([]int32, 2*n-len(d.litConstraints))...)
+	}
+	for i := start; i < len(d.constraintsInOrder); i++ {
+		d.litConstraints[d.constraintsInOrder[i]] = int32(i) + 1
+	}
+	return nil
+}
+
+// truncate removes every constraint applied after the first n, which
+// must all be global constraints applied by extend. Literals that the
+// removed constraints shared with the remaining ones are mapped back
+// to the last remaining constraint that produced them.
+func (d *litMapping) truncate(n int) {
+	for _, m := range d.constraintsInOrder[n:] {
+		d.litConstraints[m] = 0
+	}
+	d.applied = d.applied[:n]
+	d.constraintsInOrder = d.constraintsInOrder[:n]
+	d.disabled = d.disabled[:n]
+	for len(d.softs) > 0 && int(d.softs[len(d.softs)-1]) >= n {
+		d.softs = d.softs[:len(d.softs)-1]
+	}
+	for i := n - 1; i >= 0; i-- {
+		if m := d.constraintsInOrder[i]; d.litConstraints[m] == 0 {
+			d.litConstraints[m] = int32(i) + 1
+		}
+	}
+}
+
+// apply translates a constraint on the given Variable, or a global
+// constraint if the Variable is nil, after loading the Variables it
+// prefers.
+func (d *litMapping) apply(variable deppy.Variable, constraint deppy.Constraint) error {
+	if err := deppy.Validate(constraint); err != nil {
+		if variable != nil {
+			return fmt.Errorf("invalid constraint on %q: %w", variable.Identifier(), err)
+		}
+		return fmt.Errorf("invalid global constraint: %w", err)
+	}
+	for _, id := range constraint.Order() {
+		if _, ok := d.ids[id]; !ok {
+			d.load(id)
+		}
+	}
+	var subject deppy.Identifier
+	if variable != nil {
+		subject = variable.Identifier()
+	}
+	d.global, d.subjectRead = variable == nil, false
+	m := constraint.Apply(d, subject)
+	d.global = false
+	if d.sourceErr != nil {
+		return d.sourceErr
+	}
+	if d.subjectRead {
+		// Global constraints have no subject, so those that
+		// constrain their subject, such as Mandatory or
+		// Dependency, cannot be applied.
+		return fmt.Errorf("invalid global constraint %q (%T): depends on its subject", strings.TrimSpace(constraint.String(subject)), constraint)
+	}
+	index := int32(-1)
+	if m != z.LitNull {
+		index = int32(len(d.applied))
+		d.applied = append(d.applied, deppy.AppliedConstraint{
+			Variable:   variable,
+			Constraint: constraint,
+		})
+		d.constraintsInOrder = append(d.constraintsInOrder, m)
+		d.disabled = append(d.disabled, false)
+		if _, ok := deppy.PriorityOf(constraint); ok {
+			// Soft constraints are only assumed once
+			// they are known to be satisfiable.
+			d.softs = append(d.softs, index)
+			d.disabled[index] = true
+		}
+	}
+	// Otherwise, this constraint doesn't have a useful
+	// representation in the SAT inputs.
+
+	if variable != nil && constraint.Anchor() {
+		d.anchors = append(d.anchors, anchor{
+			variable: d.ids[subject],
+			applied:  index,
+		})
+	}
+	return nil
+}
+
+// add assigns a literal to the given Variable and appends it to the
+// Variables to be visited.
+func (d *litMapping) add(variable deppy.Variable) z.Lit {
+	m := d.c.Lit()
+	d.ids[variable.Identifier()] = int32(len(d.inorder))
+	d.inorder = append(d.inorder, variable)
+	d.varLits = append(d.varLits, m)
+	return m
+}
+
+// load requests the Variable with the given Identifier from the
+// source and adds it to the mapping, returning its literal, or
+// z.LitNull if the source does not provide it.
+func (d *litMapping) load(id deppy.Identifier) z.Lit {
+	if d.source == nil || d.sourceErr != nil {
+		return z.LitNull
+	}
+	variable, ok, err := d.source.Lookup(id)
+	if err != nil {
+		d.sourceErr = fmt.Errorf("failed to look up variable %q: %w", id, err)
+		return z.LitNull
+	}
+	if !ok {
+		return z.LitNull
+	}
+	if variable.Identifier() != id {
+		d.sourceErr = fmt.Errorf("lookup of variable %q returned variable %q", id, variable.Identifier())
+		return z.LitNull
+	}
+	return d.add(variable)
+}
+
 // LogicCircuit returns the lit mappings internal logic circuit
 // used by constraint for translation into boolean expressions processed by the solver
 func (d *litMapping) LogicCircuit() *logic.C {
@@ -89,31 +325,59 @@ func (d *litMapping) LogicCircuit() *logic.C {
 // LitOf returns the positive literal corresponding to the Variable
 // with the given Identifier.
 func (d *litMapping) LitOf(id deppy.Identifier) z.Lit {
-	m, ok := d.lits[id]
+	i, ok := d.ids[id]
 	if ok {
+		return d.varLits[i]
+	}
+	if id == "" && d.global {
+		d.subjectRead = true
+		return z.LitNull
+	}
+	if m := d.load(id); m != z.LitNull {
 		return m
 	}
+	if d.sourceErr != nil {
+		return z.LitNull
+	}
 	d.errs = append(d.errs, fmt.Errorf("variable %q referenced but not provided", id))
 	return z.LitNull
 }
 
+// indexOf returns the index in inorder of the Variable corresponding
+// to the provided literal, or -1 if no such Variable exists.
+func (d *litMapping) indexOf(m z.Lit) int {
+	if !m.IsPos() || int(m.Var()) >= len(d.litVars) {
+		return -1
+	}
+	return int(d.litVars[m.Var()]) - 1
+}
+
 // VariableOf returns the Variable corresponding to the provided
 // literal, or a zeroVariable if no such Variable exists.
 func (d *litMapping) VariableOf(m z.Lit) deppy.Variable {
-	i, ok := d.variables[m]
-	if ok {
-		return i
+	if i := d.indexOf(m); i >= 0 {
+		return d.inorder[i]
 	}
 	d.errs = append(d.errs, fmt.Errorf("no variable corresponding to %s", m))
 	return zeroVariable{}
 }
 
+// constraintIndexOf returns the index in applied of the constraint
+// application corresponding to the provided literal, or -1 if no
+// such constraint exists.
+func (d *litMapping) constraintIndexOf(m z.Lit) int {
+	if int(m) >= len(d.litConstraints) {
+		return -1
+	}
+	return int(d.litConstraints[m]) - 1
+}
+
 // ConstraintOf returns the constraint application corresponding to
 // the provided literal, or a zeroConstraint if no such constraint
 // exists.
 func (d *litMapping) ConstraintOf(m z.Lit) deppy.AppliedConstraint {
-	if a, ok := d.constraints[m]; ok {
-		return a
+	if i := d.constraintIndexOf(m); i >= 0 {
+		return d.applied[i]
 	}
 	d.errs = append(d.errs, fmt.Errorf("no constraint corresponding to %s", m))
 	return deppy.AppliedConstraint{
@@ -127,11 +391,15 @@ func (d *litMapping) ConstraintOf(m z.Lit) deppy.AppliedConstraint {
 // been no errors. A non-nil return value likely indicates a problem
 // with the solver or constraint implementations.
 func (d *litMapping) Error() error {
-	if len(d.errs) == 0 {
+	return aggregate(d.errs)
+}
+
+func aggregate(errs []error) error {
+	if len(errs) == 0 {
 		return nil
 	}
-	s := make([]string, len(d.errs))
-	for i, err := range d.errs {
+	s := make([]string, len(errs))
+	for i, err := range errs {
 		s[i] = err.Error()
 	}
 	return fmt.Errorf("%d errors encountered: %s", len(s), strings.Join(s, ", "))
@@ -143,10 +411,45 @@ func (d *litMapping) AddConstraints(g inter.S) {
 	d.c.ToCnf(g)
 }
 
+// AssumeConstraints assumes every constraint except the soft
+// constraints that are disabled.
 func (d *litMapping) AssumeConstraints(s inter.S) {
-	for _, m := range d.constraintsInOrder {
-		s.Assume(m)
+	if len(d.softs) == 0 {
+		s.Assume(d.constraintsInOrder...)
+		return
 	}
+	for i, m := range d.constraintsInOrder {
+		if !d.disabled[i] {
+			s.Assume(m)
+		}
+	}
+}
+
+// SoftConstraints returns the index in applied of every soft
+// constraint, highest priority first and otherwise in input order.
+func (d *litMapping) SoftConstraints() []int {
+	result := make([]int, len(d.softs))
+	for i, index := range d.softs {
+		result[i] = int(index)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		pi, _ := deppy.PriorityOf(d.applied[result[i]].Constraint)
+		pj, _ := deppy.PriorityOf(d.applied[result[j]].Constraint)
+		return pi > pj
+	})
+	return result
+}
+
+// SetEnabled includes the constraint at the given index in applied in,
+// or excludes it from, the constraints assumed by AssumeConstraints.
+func (d *litMapping) SetEnabled(index int, enabled bool) {
+	d.disabled[index] = !enabled
+}
+
+// AppliedConstraint returns the constraint application at the given
+// index in applied.
+func (d *litMapping) AppliedConstraint(index int) deppy.AppliedConstraint {
+	return d.applied[index]
 }
 
 // CardinalityConstrainer constructs a sorting network to provide
@@ -168,49 +471,67 @@ func (d *litMapping) CardinalityConstrainer(g inter.Adder, ms []z.Lit) *logic.Ca
 }
 
 // AnchorIdentifiers returns a slice containing the Identifiers of
-// every Variable with at least one "Anchor" constraint, in the
-// Order they appear in the input.
+// every Variable with at least one "Anchor" constraint that is not a
+// disabled soft constraint, in the Order they appear in the input.
 func (d *litMapping) AnchorIdentifiers() []deppy.Identifier {
 	var ids []deppy.Identifier
-	for _, variable := range d.inorder {
-		for _, constraint := range variable.Constraints() {
-			if constraint.Anchor() {
-				ids = append(ids, variable.Identifier())
-				break
-			}
+	last := int32(-1)
+	for _, a := range d.anchors {
+		if a.variable == last || (a.applied >= 0 && d.disabled[a.applied]) {
+			continue
 		}
+		ids = append(ids, d.inorder[a.variable].Identifier())
+		last = a.variable
 	}
 	return ids
 }
 
+// Preferences returns the choices expressed by the global
+// constraints, each as the literals of its candidates in order of
+// preference.
+func (d *litMapping) Preferences() [][]z.Lit {
+	result := make([][]z.Lit, len(d.preferences))
+	for i, ids := range d.preferences {
+		result[i] = make([]z.Lit, len(ids))
+		for j, id := range ids {
+			result[i][j] = d.LitOf(id)
+		}
+	}
+	return result
+}
+
 func (d *litMapping) Variables(g inter.S) []deppy.Variable {
 	var result []deppy.Variable
-	for _, i := range d.inorder {
-		if g.Value(d.LitOf(i.Identifier())) {
-			result = append(result, i)
+	for i, variable := range d.inorder {
+		if g.Value(d.varLits[i]) {
+			result = append(result, variable)
 		}
 	}
 	return result
 }
 
-func (d *litMapping) Lits(dst []z.Lit) []z.Lit {
-	if cap(dst) < len(d.inorder) {
-		dst = make([]z.Lit, 0, len(d.inorder))
-	}
-	dst = dst[:0]
-	for _, i := range d.inorder {
-		m := d.LitOf(i.Identifier())
-		dst = append(dst, m)
+// Model returns the set of positive literals of the Variables that
+// hold in the solver's current assignment.
+func (d *litMapping) Model(g inter.Model) litSet {
+	var model litSet
+	for _, m := range d.varLits {
+		if g.Value(m) {
+			model.Add(m)
+		}
 	}
-	return dst
+	return model
+}
+
+func (d *litMapping) Lits(dst []z.Lit) []z.Lit {
+	return append(dst[:0], d.varLits...)
 }
 
 func (d *litMapping) Conflicts(g inter.Assumable) []deppy.AppliedConstraint {
 	whys := g.Why(nil)
 	as := make([]deppy.AppliedConstraint, 0, len(whys))
 	for _, why := range whys {
-		if a, ok := d.constraints[why]; ok {
-			as = append(as, a)
+		if i := d.constraintIndexOf(why); i >= 0 {
+			as = append(as, d.applied[i])
 		}
 	}
 	return as
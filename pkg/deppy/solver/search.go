@@ -1,6 +1,8 @@
 package solver
 
 import (
+	"sort"
+
 	"github.com/go-air/gini/inter"
 	"github.com/go-air/gini/z"
 
@@ -8,27 +10,113 @@ import (
 )
 
 type choice struct {
-	prev, next *choice
 	index      int // index of next unguessed literal
 	candidates []z.Lit
 }
 
+// choiceDeque is a double-ended queue of choices backed by a ring
+// buffer, so that pushing and popping choices does not allocate once
+// the buffer has grown to the working size of a search.
+type choiceDeque struct {
+	buf        []choice
+	head, size int
+}
+
+func (q *choiceDeque) Len() int {
+	return q.size
+}
+
+func (q *choiceDeque) grow() {
+	if q.size < len(q.buf) {
+		return
+	}
+	buf := make([]choice, 2*len(q.buf)+8)
+	for i := 0; i < q.size; i++ {
+		buf[i] = q.buf[(q.head+i)%len(q.buf)]
+	}
+	q.buf = buf
+	q.head = 0
+}
+
+func (q *choiceDeque) PushFront(c choice) {
+	q.grow()
+	q.head = (q.head + len(q.buf) - 1) % len(q.buf)
+	q.buf[q.head] = c
+	q.size++
+}
+
+func (q *choiceDeque) PopFront() choice {
+	c := q.buf[q.head]
+	q.buf[q.head] = choice{}
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+	return c
+}
+
+func (q *choiceDeque) PushBack(c choice) {
+	q.grow()
+	q.buf[(q.head+q.size)%len(q.buf)] = c
+	q.size++
+}
+
+func (q *choiceDeque) PopBack() choice {
+	i := (q.head + q.size - 1) % len(q.buf)
+	c := q.buf[i]
+	q.buf[i] = choice{}
+	q.size--
+	return c
+}
+
+// litSet is a set of literals represented as a dense bitmap indexed
+// by z.Lit.
+type litSet []uint64
+
+func (s litSet) Contains(m z.Lit) bool {
+	i := int(m >> 6)
+	return i < len(s) && s[i]&(1<<(m&63)) != 0
+}
+
+func (s *litSet) Add(m z.Lit) {
+	i := int(m >> 6)
+	if i >= len(*s) {
+		*s = append(*s, make(litSet, i+1-len(*s))...)
+	}
+	(*s)[i] |= 1 << (m & 63)
+}
+
+func (s litSet) Remove(m z.Lit) {
+	if i := int(m >> 6); i < len(s) {
+		s[i] &^= 1 << (m & 63)
+	}
+}
+
 type guess struct {
 	m          z.Lit // if z.LitNull, this choice was satisfied by a previous assumption
 	index      int   // index of guessed literal in candidates
 	children   int   // number of choices introduced by making this guess
+	mark       int   // length of the candidate arena before this guess was made
 	candidates []z.Lit
 }
 
 type search struct {
-	s                      inter.S
-	lits                   *litMapping
-	assumptions            map[z.Lit]struct{} // set of assumed lits - duplicates guess stack - for fast lookup
-	guesses                []guess            // stack of assumed guesses
-	headChoice, tailChoice *choice            // deque of unmade choices
-	tracer                 deppy.Tracer
-	result                 int
-	buffer                 []z.Lit
+	s           inter.S
+	lits        *litMapping
+	assumptions litSet      // set of assumed lits - duplicates guess stack - for fast lookup
+	guesses     []guess     // stack of assumed guesses
+	choices     choiceDeque // deque of unmade choices
+	// arena backs the candidates of every choice introduced by a
+	// guess. Guesses are made and unmade in stack order, so the
+	// arena is truncated back to a guess's mark when it is popped.
+	arena []z.Lit
+	// preferences are choices to be made after the anchors, such
+	// as those expressed by global constraints.
+	preferences [][]z.Lit
+	// prefer, if not nil, reorders the candidates of every choice
+	// by a global preference.
+	prefer func(a, b deppy.Identifier) bool
+	tracer deppy.Tracer
+	result int
+	buffer []z.Lit
 }
 
 func (h *search) PushGuess() {
@@ -36,6 +124,7 @@ func (h *search) PushGuess() {
 	g := guess{
 		m:          z.LitNull,
 		index:      c.index,
+		mark:       len(h.arena),
 		candidates: c.candidates,
 	}
 	if g.index < len(g.candidates) {
@@ -45,7 +134,7 @@ func (h *search) PushGuess() {
 	// Check whether or not this choice can be satisfied by an
 	// existing assumption.
 	for _, m := range g.candidates {
-		if _, ok := h.assumptions[m]; ok {
+		if h.assumptions.Contains(m) {
 			g.m = z.LitNull
 			break
 		}
@@ -58,35 +147,59 @@ func (h *search) PushGuess() {
 
 	variable := h.lits.VariableOf(g.m)
 	for _, constraint := range variable.Constraints() {
-		var ms []z.Lit
-		for _, dependency := range constraint.Order() {
-			ms = append(ms, h.lits.LitOf(dependency))
-		}
-		if len(ms) > 0 {
-			h.guesses[len(h.guesses)-1].children++
-			h.PushChoiceBack(choice{candidates: ms})
+		if cc, ok := constraint.(deppy.ChoiceConstraint); ok {
+			for _, candidates := range cc.Choices() {
+				h.pushCandidates(candidates)
+			}
+			continue
 		}
+		h.pushCandidates(constraint.Order())
 	}
 
-	if h.assumptions == nil {
-		h.assumptions = make(map[z.Lit]struct{})
-	}
-	h.assumptions[g.m] = struct{}{}
+	h.assumptions.Add(g.m)
 	h.s.Assume(g.m)
 	h.result, h.buffer = h.s.Test(h.buffer)
 }
 
+// pushCandidates appends a choice among the Variables with the given
+// Identifiers to the back of the deque, as a child of the most recent
+// guess.
+func (h *search) pushCandidates(ids []deppy.Identifier) {
+	if len(ids) == 0 {
+		return
+	}
+	start := len(h.arena)
+	for _, id := range ids {
+		h.arena = append(h.arena, h.lits.LitOf(id))
+	}
+	h.sortCandidates(h.arena[start:])
+	h.guesses[len(h.guesses)-1].children++
+	h.PushChoiceBack(choice{candidates: h.arena[start:len(h.arena):len(h.arena)]})
+}
+
+// sortCandidates orders candidates by the global preference, if any,
+// keeping the order of candidates that are equally preferred.
+func (h *search) sortCandidates(candidates []z.Lit) {
+	if h.prefer == nil {
+		return
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return h.prefer(h.lits.VariableOf(candidates[i]).Identifier(), h.lits.VariableOf(candidates[j]).Identifier())
+	})
+}
+
 func (h *search) PopGuess() {
 	g := h.guesses[len(h.guesses)-1]
 	h.guesses = h.guesses[:len(h.guesses)-1]
 	if g.m != z.LitNull {
-		delete(h.assumptions, g.m)
+		h.assumptions.Remove(g.m)
 		h.result = h.s.Untest()
 	}
 	for g.children > 0 {
 		g.children--
 		h.PopChoiceBack()
 	}
+	h.arena = h.arena[:g.mark]
 	c := choice{
 		index:      g.index,
 		candidates: g.candidates,
@@ -98,47 +211,19 @@ func (h *search) PopGuess() {
 }
 
 func (h *search) PushChoiceFront(c choice) {
-	if h.headChoice == nil {
-		h.headChoice = &c
-		h.tailChoice = &c
-		return
-	}
-	h.headChoice.prev = &c
-	c.next = h.headChoice
-	h.headChoice = &c
+	h.choices.PushFront(c)
 }
 
 func (h *search) PopChoiceFront() choice {
-	c := h.headChoice
-	if c.next != nil {
-		c.next.prev = nil
-	} else {
-		h.tailChoice = nil
-	}
-	h.headChoice = c.next
-	return *c
+	return h.choices.PopFront()
 }
 
 func (h *search) PushChoiceBack(c choice) {
-	if h.tailChoice == nil {
-		h.headChoice = &c
-		h.tailChoice = &c
-		return
-	}
-	h.tailChoice.next = &c
-	c.prev = h.tailChoice
-	h.tailChoice = &c
+	h.choices.PushBack(c)
 }
 
 func (h *search) PopChoiceBack() choice {
-	c := h.tailChoice
-	if c.prev != nil {
-		c.prev.next = nil
-	} else {
-		h.headChoice = nil
-	}
-	h.tailChoice = c.prev
-	return *c
+	return h.choices.PopBack()
 }
 
 func (h *search) Result() int {
@@ -155,16 +240,27 @@ func (h *search) Lits() []z.Lit {
 	return result
 }
 
-func (h *search) Do(anchors []z.Lit) (int, []z.Lit, map[z.Lit]struct{}) {
-	for _, m := range anchors {
-		h.PushChoiceBack(choice{candidates: []z.Lit{m}})
+// Do searches for a solution that respects the preferences expressed
+// through the Order of each guessed Variable's constraints. It
+// returns the outcome, the guessed literals, the same literals as a
+// set, and, if a solution was found, the set of Variable literals
+// that hold in it. The model is captured before the search returns to
+// the initial test scope, since the solver's values are no longer
+// meaningful afterwards.
+func (h *search) Do(anchors []z.Lit) (int, []z.Lit, litSet, litSet) {
+	for i := range anchors {
+		h.PushChoiceBack(choice{candidates: anchors[i : i+1 : i+1]})
+	}
+	for _, candidates := range h.preferences {
+		h.sortCandidates(candidates)
+		h.PushChoiceBack(choice{candidates: candidates})
 	}
 
 	for {
 		// Need to have a definitive result once all choices
 		// have been made to decide whether to end or
 		// backtrack.
-		if h.headChoice == nil && h.result == unknown {
+		if h.choices.Len() == 0 && h.result == unknown {
 			h.result = h.s.Solve()
 		}
 
@@ -179,7 +275,7 @@ func (h *search) Do(anchors []z.Lit) (int, []z.Lit, map[z.Lit]struct{}) {
 		}
 
 		// Satisfiable and no decisions left!
-		if h.headChoice == nil {
+		if h.choices.Len() == 0 {
 			break
 		}
 
@@ -188,18 +284,22 @@ func (h *search) Do(anchors []z.Lit) (int, []z.Lit, map[z.Lit]struct{}) {
 	}
 
 	lits := h.Lits()
-	set := make(map[z.Lit]struct{}, len(lits))
+	var set litSet
 	for _, m := range lits {
-		set[m] = struct{}{}
+		set.Add(m)
 	}
 	result := h.Result()
+	var model litSet
+	if result == satisfiable {
+		model = h.lits.Model(h.s)
+	}
 
 	// Go back to the initial test scope.
 	for len(h.guesses) > 0 {
 		h.PopGuess()
 	}
 
-	return result, lits, set
+	return result, lits, set, model
 }
 
 func (h *search) Variables() []deppy.Variable {
@@ -95,7 +95,7 @@ func TestSearch(t *testing.T) {
 				anchors = append(anchors, h.lits.LitOf(id))
 			}
 
-			result, ms, _ := h.Do(anchors)
+			result, ms, _, _ := h.Do(anchors)
 
 			assert.Equal(tt.Result, result)
 			var ids []deppy.Identifier
@@ -3,6 +3,8 @@ package solver
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/go-air/gini"
 	"github.com/go-air/gini/inter"
@@ -13,6 +15,7 @@ import (
 
 type Solver struct {
 	tracer deppy.Tracer
+	prefer func(a, b deppy.Identifier) bool
 }
 
 const (
@@ -21,16 +24,85 @@ const (
 	unknown       = 0
 )
 
+// Result is the outcome of a successful resolution.
+type Result struct {
+	// Selection holds the Variables that were selected for
+	// installation.
+	Selection []deppy.Variable
+	// Dropped holds the soft constraints, such as Optional
+	// anchors, that could not be satisfied together with the
+	// constraints of higher priority, highest priority first.
+	Dropped []DroppedConstraint
+}
+
+// DroppedConstraint is a soft constraint that was not satisfied,
+// along with a set of constraints that conflicted with it.
+type DroppedConstraint struct {
+	Constraint deppy.AppliedConstraint
+	Conflicts  deppy.NotSatisfiable
+}
+
+func (d DroppedConstraint) String() string {
+	s := make([]string, len(d.Conflicts))
+	for i, a := range d.Conflicts {
+		s[i] = a.String()
+	}
+	return fmt.Sprintf("%s: dropped due to conflicts:\n%s", d.Constraint, strings.Join(s, "\n"))
+}
+
 // Solve takes a slice containing all Variables and returns a slice
 // containing only those Variables that were selected for
 // installation. If no solution is possible an error is returned.
+// Soft constraints that cannot be satisfied are silently dropped.
 func (s *Solver) Solve(input []deppy.Variable) ([]deppy.Variable, error) {
-	giniSolver := gini.New()
-	litMap, err := newLitMapping(input)
+	return s.SolveProblem(deppy.Problem{Variables: input})
+}
+
+// SolveProblem behaves like Solve, but additionally enforces the
+// global Constraints of the given Problem. Global Constraints appear
+// in NotSatisfiable errors with a nil Variable.
+func (s *Solver) SolveProblem(problem deppy.Problem) ([]deppy.Variable, error) {
+	result, err := s.Resolve(problem)
+	if err != nil {
+		return nil, err
+	}
+	return result.Selection, nil
+}
+
+// Resolve behaves like SolveProblem, but also reports the soft
+// constraints that had to be dropped to find a solution. Soft
+// constraints are considered one at a time, highest priority first,
+// and each is kept if it can be satisfied together with the hard
+// constraints and the soft constraints kept so far.
+func (s *Solver) Resolve(problem deppy.Problem) (*Result, error) {
+	litMap, err := newLitMapping(problem.Variables, problem.Constraints...)
+	if err != nil {
+		return nil, err
+	}
+	return s.solveLitMapping(litMap)
+}
+
+// SolveSource behaves like Solve, but rather than taking every
+// Variable up front, it starts from the initial Variables of the
+// given source and requests each further Variable by Identifier as
+// it is referenced by the constraints or the preference Order of a
+// Variable already loaded. Variables that are not reachable this way
+// are never requested. Selected Variables are returned in the order
+// in which they were loaded.
+func (s *Solver) SolveSource(source deppy.VariableSource) ([]deppy.Variable, error) {
+	litMap, err := newLitMappingFromSource(source, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.solveLitMapping(litMap)
 	if err != nil {
 		return nil, err
 	}
+	return result.Selection, nil
+}
 
+func (s *Solver) solveLitMapping(litMap *litMapping) (*Result, error) {
+	giniSolver := gini.New()
 	result, err := s.solve(giniSolver, litMap)
 
 	// This likely indicates a bug, so discard whatever
@@ -42,10 +114,67 @@ func (s *Solver) Solve(input []deppy.Variable) ([]deppy.Variable, error) {
 	return result, err
 }
 
-func (s *Solver) solve(giniSolver inter.S, litMap *litMapping) ([]deppy.Variable, error) {
+// relax decides which soft constraints to keep, enabling each in turn,
+// highest priority first, and disabling it again if the problem
+// becomes unsatisfiable with the constraint's subject selected. It
+// returns the soft constraints that were dropped, or an error if the
+// problem is unsatisfiable even without any soft constraints.
+func (s *Solver) relax(giniSolver inter.S, litMap *litMapping) ([]DroppedConstraint, error) {
+	softs := litMap.SoftConstraints()
+	if len(softs) == 0 {
+		return nil, nil
+	}
+
+	litMap.AssumeConstraints(giniSolver)
+	if giniSolver.Solve() == unsatisfiable {
+		return nil, deppy.NotSatisfiable(litMap.Conflicts(giniSolver))
+	}
+
+	var dropped []DroppedConstraint
+	for _, index := range softs {
+		litMap.SetEnabled(index, true)
+		litMap.AssumeConstraints(giniSolver)
+		if a := litMap.AppliedConstraint(index); a.Variable != nil {
+			// A soft constraint on a Variable only matters
+			// if that Variable is selected.
+			giniSolver.Assume(litMap.LitOf(a.Variable.Identifier()))
+		}
+		if giniSolver.Solve() == unsatisfiable {
+			dropped = append(dropped, DroppedConstraint{
+				Constraint: litMap.AppliedConstraint(index),
+				Conflicts:  litMap.Conflicts(giniSolver),
+			})
+			litMap.SetEnabled(index, false)
+		}
+	}
+	return dropped, nil
+}
+
+// relevant returns the dropped constraints that are worth reporting
+// for the solution in the given model: dropped anchors, dropped global
+// constraints, and dropped constraints on selected Variables. A
+// dropped recommendation of a Variable that was not selected anyway
+// makes no difference to the solution.
+func relevant(model inter.Model, litMap *litMapping, dropped []DroppedConstraint) []DroppedConstraint {
+	var result []DroppedConstraint
+	for _, d := range dropped {
+		v := d.Constraint.Variable
+		if v == nil || d.Constraint.Constraint.Anchor() || model.Value(litMap.LitOf(v.Identifier())) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func (s *Solver) solve(giniSolver inter.S, litMap *litMapping) (*Result, error) {
 	// teach all constraints to the solver
 	litMap.AddConstraints(giniSolver)
 
+	dropped, err := s.relax(giniSolver, litMap)
+	if err != nil {
+		return nil, err
+	}
+
 	// collect literals of all mandatory variables to assume as a baseline
 	anchors := litMap.AnchorIdentifiers()
 	assumptions := make([]z.Lit, len(anchors))
@@ -58,23 +187,28 @@ func (s *Solver) solve(giniSolver inter.S, litMap *litMapping) ([]deppy.Variable
 	giniSolver.Assume(assumptions...)
 
 	var buffer []z.Lit
-	var aset map[z.Lit]struct{}
+	var aset, model litSet
 	// push a new test scope with the baseline assumptions, to prevent them from being cleared during search
 	outcome, _ := giniSolver.Test(nil)
 	if outcome != satisfiable && outcome != unsatisfiable {
 		// searcher for solutions in input Order, so that preferences
 		// can be taken into account (i.e. prefer one catalog to another)
-		outcome, assumptions, aset = (&search{s: giniSolver, lits: litMap, tracer: s.tracer}).Do(assumptions)
+		outcome, assumptions, aset, model = (&search{s: giniSolver, lits: litMap, preferences: litMap.Preferences(), prefer: s.prefer, tracer: s.tracer}).Do(assumptions)
+	} else if outcome == satisfiable {
+		model = litMap.Model(giniSolver)
 	}
 	switch outcome {
 	case satisfiable:
 		buffer = litMap.Lits(buffer)
 		var extras, excluded []z.Lit
 		for _, m := range buffer {
-			if _, ok := aset[m]; ok {
+			if aset.Contains(m) {
 				continue
 			}
-			if !giniSolver.Value(m) {
+			if !model.Contains(m) && s.prefer == nil {
+				// Any subset of the model found by search
+				// will do, unless a preference may favor a
+				// solution outside of it.
 				excluded = append(excluded, m.Not())
 				continue
 			}
@@ -89,7 +223,13 @@ func (s *Solver) solve(giniSolver inter.S, litMap *litMapping) ([]deppy.Variable
 		for w := 0; w <= cs.N(); w++ {
 			giniSolver.Assume(cs.Leq(w))
 			if giniSolver.Solve() == satisfiable {
-				return litMap.Variables(giniSolver), nil
+				if s.prefer != nil {
+					s.preferExtras(giniSolver, litMap, extras, cs.Leq(w))
+				}
+				return &Result{
+					Selection: litMap.Variables(giniSolver),
+					Dropped:   relevant(giniSolver, litMap, dropped),
+				}, nil
 			}
 		}
 		// Something is wrong if we can't find a model anymore
@@ -103,6 +243,32 @@ func (s *Solver) solve(giniSolver inter.S, litMap *litMapping) ([]deppy.Variable
 	return nil, errors.New("unknown outcome")
 }
 
+// preferExtras settles each of the extra Variables in order of the
+// global preference, selecting it if there is still a solution within
+// the given cardinality bound, so that the most preferred of the
+// solutions of minimal cardinality is found. The solver is left with a
+// model of that solution.
+func (s *Solver) preferExtras(giniSolver inter.S, litMap *litMapping, extras []z.Lit, bound z.Lit) {
+	sorted := make([]z.Lit, len(extras))
+	copy(sorted, extras)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return s.prefer(litMap.VariableOf(sorted[i]).Identifier(), litMap.VariableOf(sorted[j]).Identifier())
+	})
+
+	fixed := []z.Lit{bound}
+	for _, m := range sorted {
+		giniSolver.Assume(fixed...)
+		giniSolver.Assume(m)
+		if giniSolver.Solve() == satisfiable {
+			fixed = append(fixed, m)
+		} else {
+			fixed = append(fixed, m.Not())
+		}
+	}
+	giniSolver.Assume(fixed...)
+	giniSolver.Solve()
+}
+
 func New(options ...Option) (*Solver, error) {
 	s := Solver{}
 	for _, option := range append(options, defaults...) {
@@ -122,6 +288,23 @@ func WithTracer(t deppy.Tracer) Option {
 	}
 }
 
+// WithPreference configures a global preference between Variables,
+// where less reports whether the Variable identified by a is
+// preferred over the one identified by b. The preference reorders the
+// candidates of every choice made during search, keeping the Order of
+// candidates that are equally preferred, and decides between
+// solutions that select the same number of Variables beyond those
+// chosen by search. Since every Variable not chosen by search then
+// takes part in that decision, rather than only those in the first
+// solution found, a preference makes the minimization phase more
+// expensive.
+func WithPreference(less func(a, b deppy.Identifier) bool) Option {
+	return func(s *Solver) error {
+		s.prefer = less
+		return nil
+	}
+}
+
 var defaults = []Option{
 	func(s *Solver) error {
 		if s.tracer == nil {
@@ -2,13 +2,20 @@ package solver
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint/expr"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
 )
@@ -80,6 +87,17 @@ func TestNotSatisfiableError(t *testing.T) {
 			String: fmt.Sprintf("constraints not satisfiable:\n%s\n%s",
 				constraint.Mandatory().String("a"), constraint.Prohibited().String("b")),
 		},
+		{
+			Name: "failure with metadata",
+			Error: deppy.NotSatisfiable{
+				deppy.AppliedConstraint{
+					Variable:   variable("a"),
+					Constraint: constraint.WithMetadata(constraint.Prohibited(), policyMetadata),
+				},
+			},
+			String: fmt.Sprintf("constraints not satisfiable:\n%s (origin: cluster policy; reason: a is deprecated; labels: scope=cluster,severity=high; see https://example.com/policy)",
+				constraint.Prohibited().String("a")),
+		},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			assert.Equal(t, tt.String, tt.Error.Error())
@@ -87,6 +105,87 @@ func TestNotSatisfiableError(t *testing.T) {
 	}
 }
 
+var policyMetadata = deppy.Metadata{
+	Origin:           "cluster policy",
+	Reason:           "a is deprecated",
+	Labels:           map[string]string{"severity": "high", "scope": "cluster"},
+	DocumentationURL: "https://example.com/policy",
+}
+
+func TestNotSatisfiableJSON(t *testing.T) {
+	err := deppy.NotSatisfiable{
+		{
+			Variable:   variable("a"),
+			Constraint: constraint.WithMetadata(constraint.Prohibited(), policyMetadata),
+		},
+		{
+			Variable:   variable("b"),
+			Constraint: constraint.Mandatory(),
+		},
+	}
+
+	b, jerr := json.Marshal(err)
+	require.NoError(t, jerr)
+	assert.JSONEq(t, `[
+		{
+			"variable": "a",
+			"constraint": "a is ProhibitedConstraint",
+			"metadata": {
+				"origin": "cluster policy",
+				"reason": "a is deprecated",
+				"labels": {"scope": "cluster", "severity": "high"},
+				"documentationURL": "https://example.com/policy"
+			}
+		},
+		{
+			"variable": "b",
+			"constraint": "b is mandatory"
+		}
+	]`, string(b))
+}
+
+func TestSolveReportsMetadata(t *testing.T) {
+	requested := constraint.WithMetadata(constraint.Dependency("a", "b"), deppy.Metadata{Origin: "user request"})
+	limited := constraint.WithMetadata(constraint.AtMost(1, "x", "y"), policyMetadata)
+
+	t.Run("in conflicts", func(t *testing.T) {
+		s, err := New()
+		require.NoError(t, err)
+		_, err = s.Solve([]deppy.Variable{
+			variable("request", constraint.Mandatory(), requested, limited),
+			variable("a", constraint.Implies("x", "y")),
+			variable("b", constraint.Prohibited()),
+			variable("x"),
+			variable("y"),
+		})
+
+		var ns deppy.NotSatisfiable
+		require.ErrorAs(t, err, &ns)
+		var origins []string
+		for _, a := range ns {
+			origins = append(origins, a.Metadata().Origin)
+		}
+		assert.Contains(t, origins, "user request")
+		assert.Contains(t, origins, "cluster policy")
+	})
+
+	t.Run("in traces", func(t *testing.T) {
+		var traces bytes.Buffer
+		s, err := New(WithTracer(LoggingTracer{Writer: &traces}))
+		require.NoError(t, err)
+		installed, err := s.Solve([]deppy.Variable{
+			variable("request", constraint.Mandatory(), requested, limited),
+			variable("a", constraint.Implies("x", "y")),
+			variable("b"),
+			variable("x"),
+			variable("y"),
+		})
+		require.NoError(t, err)
+		assert.Len(t, installed, 2)
+		assert.Contains(t, traces.String(), "- request permits at most 1 of x, y (origin: cluster policy;")
+	})
+}
+
 func TestSolve(t *testing.T) {
 	type tc struct {
 		Name      string
@@ -286,6 +385,216 @@ func TestSolve(t *testing.T) {
 			},
 			Installed: []deppy.Identifier{"a", "x1", "y1"},
 		},
+		{
+			Name: "all implied variables are installed",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Implies("x", "y")),
+				variable("x"),
+				variable("y"),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "x", "y"},
+		},
+		{
+			Name: "preferences of every implied variable are respected",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Implies("x", "y")),
+				variable("x", constraint.Dependency("x1", "x2")),
+				variable("y", constraint.Dependency("y1", "y2")),
+				variable("x1"),
+				variable("x2"),
+				variable("y1"),
+				variable("y2"),
+			},
+			Installed: []deppy.Identifier{"a", "x", "y", "x1", "y1"},
+		},
+		{
+			Name: "excluded variables produce a single conflict",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Excludes("x", "y")),
+				variable("x"),
+				variable("y", constraint.Mandatory()),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("y", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.Excludes("x", "y")),
+					Constraint: constraint.Excludes("x", "y"),
+				},
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.Excludes("x", "y")),
+					Constraint: constraint.Mandatory(),
+				},
+			},
+		},
+		{
+			Name: "equivalent variable is installed",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Iff("x")),
+				variable("x"),
+			},
+			Installed: []deppy.Identifier{"a", "x"},
+		},
+		{
+			Name: "equivalence is symmetric",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Iff("x")),
+				variable("x", constraint.Mandatory()),
+			},
+			Installed: []deppy.Identifier{"a", "x"},
+		},
+		{
+			Name: "exclusive alternative is installed",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Prohibited(), constraint.Xor("x")),
+				variable("x"),
+			},
+			Installed: []deppy.Identifier{"x"},
+		},
+		{
+			Name: "variables propagated by a guess are installed",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("b", "c")),
+				variable("b", constraint.Conflict("y")),
+				variable("c"),
+				variable("x", constraint.Xor("y")),
+				variable("y"),
+			},
+			Installed: []deppy.Identifier{"a", "b", "x"},
+		},
+		{
+			Name: "at least constraint installs preferred variables",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.AtLeast(2, "x", "y", "z")),
+				variable("x"),
+				variable("y", constraint.Prohibited()),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "x", "z"},
+		},
+		{
+			Name: "exactly constraint prevents resolution in a single conflict",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Exactly(1, "x", "y")),
+				variable("x", constraint.Mandatory()),
+				variable("y", constraint.Mandatory()),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("y", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("x", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.Exactly(1, "x", "y")),
+					Constraint: constraint.Exactly(1, "x", "y"),
+				},
+			},
+		},
+		{
+			Name: "between constraint bounds the selection",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Between(1, 2, "x", "y", "z")),
+				variable("x", constraint.Mandatory()),
+				variable("y", constraint.Mandatory()),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "x", "y"},
+		},
+		{
+			Name: "weighted at most constraint limits the selection",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("x", "y"), constraint.Dependency("z"),
+					constraint.WeightedAtMost(64, map[deppy.Identifier]int{"x": 48, "y": 16, "z": 32})),
+				variable("x"),
+				variable("y"),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "y", "z"},
+		},
+		{
+			Name: "weighted at most constraint reports its weights in conflicts",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.WeightedAtMost(5, map[deppy.Identifier]int{"x": 4, "y": 2})),
+				variable("x", constraint.Mandatory()),
+				variable("y", constraint.Mandatory()),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("y", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("x", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.WeightedAtMost(5, map[deppy.Identifier]int{"x": 4, "y": 2})),
+					Constraint: constraint.WeightedAtMost(5, map[deppy.Identifier]int{"x": 4, "y": 2}),
+				},
+			},
+		},
+		{
+			Name: "weighted at least constraint installs the heaviest variables",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.WeightedAtLeast(5, map[deppy.Identifier]int{"x": 1, "y": 5, "z": 2})),
+				variable("x"),
+				variable("y"),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "y"},
+		},
+		{
+			Name: "user friendly constraint preserves the choices of the wrapped constraint",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.NewUserFriendlyConstraint(constraint.Implies("x", "y"), func(_ deppy.Constraint, subject deppy.Identifier) string {
+					return fmt.Sprintf("%s needs x and y", subject)
+				})),
+				variable("x", constraint.Dependency("p", "y")),
+				variable("y"),
+				variable("p"),
+			},
+			Installed: []deppy.Identifier{"a", "x", "y"},
+		},
+		{
+			Name: "expression prefers its first alternative",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Expr(expr.Implies{
+					If: expr.And{expr.Subject{}, expr.Not{Operand: expr.Var("e")}},
+					Then: expr.Or{
+						expr.Var("b"),
+						expr.And{expr.Var("c"), expr.Var("d")},
+					},
+				})),
+				variable("b"),
+				variable("c"),
+				variable("d"),
+				variable("e"),
+			},
+			Installed: []deppy.Identifier{"a", "b"},
+		},
+		{
+			Name: "expression falls back to its second alternative",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Expr(expr.Implies{
+					If: expr.Subject{},
+					Then: expr.Or{
+						expr.Var("b"),
+						expr.And{expr.Var("c"), expr.Var("d")},
+					},
+				})),
+				variable("b", constraint.Prohibited()),
+				variable("c"),
+				variable("d"),
+			},
+			Installed: []deppy.Identifier{"a", "c", "d"},
+		},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			assert := assert.New(t)
@@ -322,3 +631,712 @@ func TestDuplicateIdentifier(t *testing.T) {
 	})
 	assert.Equal(t, DuplicateIdentifier("a"), err)
 }
+
+func TestSolveSource(t *testing.T) {
+	catalog := map[deppy.Identifier]deppy.Variable{}
+	for _, v := range []deppy.Variable{
+		variable("a", constraint.Dependency("a1", "a2")),
+		variable("a1", constraint.Conflict("c")),
+		variable("a2"),
+		variable("c", constraint.Dependency("d")),
+		variable("d"),
+		variable("unreachable", constraint.Mandatory()),
+	} {
+		catalog[v.Identifier()] = v
+	}
+
+	type tc struct {
+		Name      string
+		Initial   []deppy.Variable
+		Installed []deppy.Identifier
+		Requested []deppy.Identifier
+		Error     error
+	}
+
+	for _, tt := range []tc{
+		{
+			Name:      "only reachable variables are requested",
+			Initial:   []deppy.Variable{variable("root", constraint.Mandatory(), constraint.Dependency("a"))},
+			Installed: []deppy.Identifier{"root", "a", "a1"},
+			Requested: []deppy.Identifier{"a", "a1", "a2", "c", "d"},
+		},
+		{
+			Name:      "initial variables are not requested",
+			Initial:   []deppy.Variable{variable("root", constraint.Mandatory(), constraint.Dependency("a2")), catalog["a2"]},
+			Installed: []deppy.Identifier{"root", "a2"},
+		},
+		{
+			Name:      "missing variable is reported",
+			Initial:   []deppy.Variable{variable("root", constraint.Mandatory(), constraint.Conflict("missing"))},
+			Requested: []deppy.Identifier{"missing"},
+			Error:     errors.New(`1 errors encountered: variable "missing" referenced but not provided`),
+		},
+		{
+			Name:    "duplicate initial variable",
+			Initial: []deppy.Variable{variable("a"), catalog["a"]},
+			Error:   DuplicateIdentifier("a"),
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var requested []deppy.Identifier
+			source := input.NewLazyVariableSource(func(id deppy.Identifier) (deppy.Variable, bool, error) {
+				requested = append(requested, id)
+				v, ok := catalog[id]
+				return v, ok, nil
+			}, tt.Initial...)
+
+			s, err := New()
+			require.NoError(t, err)
+			installed, err := s.SolveSource(source)
+
+			var ids []deppy.Identifier
+			for _, variable := range installed {
+				ids = append(ids, variable.Identifier())
+			}
+			assert.Equal(tt.Installed, ids)
+			assert.Equal(tt.Requested, requested)
+			assert.Equal(tt.Error, err)
+		})
+	}
+}
+
+func TestSolveSourceLookupError(t *testing.T) {
+	source := input.NewLazyVariableSource(func(id deppy.Identifier) (deppy.Variable, bool, error) {
+		return nil, false, errors.New("catalog unavailable")
+	}, variable("root", constraint.Mandatory(), constraint.Dependency("a")))
+
+	s, err := New()
+	require.NoError(t, err)
+	_, err = s.SolveSource(source)
+	assert.EqualError(t, err, `failed to look up variable "a": catalog unavailable`)
+}
+
+// must returns the given Constraint, and panics if it could not be
+// built.
+func must(c deppy.Constraint, err error) deppy.Constraint {
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestSolveDomainVariables(t *testing.T) {
+	values := []string{"1", "2", "3"}
+
+	type tc struct {
+		Name      string
+		Domains   func() []*input.DomainVariable
+		Installed map[deppy.Identifier]string
+		Error     string
+	}
+
+	for _, tt := range []tc{
+		{
+			Name: "preferred values are taken",
+			Domains: func() []*input.DomainVariable {
+				return []*input.DomainVariable{
+					input.NewDomainVariable("x", values, constraint.Mandatory()),
+					input.NewDomainVariable("y", values),
+				}
+			},
+			Installed: map[deppy.Identifier]string{"x": "1"},
+		},
+		{
+			Name: "values satisfy value constraints",
+			Domains: func() []*input.DomainVariable {
+				x := input.NewDomainVariable("x", values, constraint.Mandatory())
+				x.AddConstraint(must(x.NotEquals("1")))
+				y := input.NewDomainVariable("y", values, constraint.Mandatory())
+				y.AddConstraint(must(y.LessThan("3")))
+				y.AddConstraint(must(y.NotEquals("1")))
+				z := input.NewDomainVariable("z", values, constraint.Mandatory())
+				z.AddConstraint(must(z.Equals("3")))
+				return []*input.DomainVariable{x, y, z}
+			},
+			Installed: map[deppy.Identifier]string{"x": "2", "y": "2", "z": "3"},
+		},
+		{
+			Name: "values satisfy relations between variables",
+			Domains: func() []*input.DomainVariable {
+				x := input.NewDomainVariable("x", values, constraint.Mandatory())
+				y := input.NewDomainVariable("y", values, constraint.Mandatory())
+				z := input.NewDomainVariable("z", values, constraint.Mandatory())
+				z.AddConstraint(z.LessThanVariable(y))
+				y.AddConstraint(y.LessThanVariable(x))
+				return []*input.DomainVariable{x, y, z}
+			},
+			Installed: map[deppy.Identifier]string{"x": "3", "y": "2", "z": "1"},
+		},
+		{
+			Name: "equal variables take the value preferred by the first",
+			Domains: func() []*input.DomainVariable {
+				x := input.NewDomainVariable("x", values, constraint.Mandatory())
+				x.AddConstraint(must(x.NotEquals("1")))
+				y := input.NewDomainVariable("y", []string{"3", "2", "1"}, constraint.Mandatory())
+				y.AddConstraint(y.EqualsVariable(x))
+				return []*input.DomainVariable{x, y}
+			},
+			Installed: map[deppy.Identifier]string{"x": "2", "y": "2"},
+		},
+		{
+			Name: "pigeonhole is not satisfiable",
+			Domains: func() []*input.DomainVariable {
+				var ds []*input.DomainVariable
+				for _, id := range []deppy.Identifier{"w", "x", "y", "z"} {
+					d := input.NewDomainVariable(id, values, constraint.Mandatory())
+					for _, other := range ds {
+						d.AddConstraint(d.NotEqualsVariable(other))
+					}
+					ds = append(ds, d)
+				}
+				return ds
+			},
+			Error: "constraints not satisfiable",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			domains := tt.Domains()
+			var variables []deppy.Variable
+			for _, d := range domains {
+				variables = append(variables, d.Variables()...)
+			}
+
+			s, err := New()
+			require.NoError(t, err)
+			installed, err := s.Solve(variables)
+			if tt.Error != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.Installed, input.DomainValues(installed, domains...))
+		})
+	}
+}
+
+func TestSolveProblem(t *testing.T) {
+	type tc struct {
+		Name      string
+		Problem   deppy.Problem
+		Installed []deppy.Identifier
+		Error     string
+	}
+
+	for _, tt := range []tc{
+		{
+			Name: "no global constraints",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Mandatory(), constraint.Dependency("b")),
+					variable("b"),
+				},
+			},
+			Installed: []deppy.Identifier{"a", "b"},
+		},
+		{
+			Name: "global constraint limits the selection",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Mandatory(), constraint.Dependency("x", "y")),
+					variable("b", constraint.Mandatory(), constraint.Dependency("x", "y")),
+					variable("x", constraint.Conflict("a")),
+					variable("y"),
+				},
+				Constraints: []deppy.Constraint{
+					constraint.AtMost(1, "x", "y"),
+				},
+			},
+			Installed: []deppy.Identifier{"a", "b", "y"},
+		},
+		{
+			Name: "global constraint preferences are followed",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("x"),
+					variable("y"),
+					variable("z"),
+				},
+				Constraints: []deppy.Constraint{
+					constraint.AtLeast(1, "z", "y", "x"),
+				},
+			},
+			Installed: []deppy.Identifier{"z"},
+		},
+		{
+			Name: "mutually exclusive group conflicts in a single message",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Mandatory(), constraint.Dependency("x")),
+					variable("b", constraint.Mandatory(), constraint.Dependency("z")),
+					variable("x"),
+					variable("y"),
+					variable("z"),
+				},
+				Constraints: []deppy.Constraint{
+					constraint.MutuallyExclusive("x", "y", "z"),
+				},
+			},
+			Error: "constraints not satisfiable:\na is mandatory\na requires at least one of x\nat most one of x, y, z may be selected\nb is mandatory\nb requires at least one of z",
+		},
+		{
+			Name: "conflicts with any excludes the whole set",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("a", constraint.Mandatory(), constraint.ConflictsWithAny("x1", "x2")),
+					variable("b", constraint.Mandatory(), constraint.Dependency("x1", "x2", "y")),
+					variable("x1"),
+					variable("x2"),
+					variable("y"),
+				},
+			},
+			Installed: []deppy.Identifier{"a", "b", "y"},
+		},
+		{
+			Name: "global constraint appears in conflicts without a subject",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("x", constraint.Mandatory()),
+					variable("y", constraint.Mandatory()),
+				},
+				Constraints: []deppy.Constraint{
+					constraint.AtMost(1, "x", "y"),
+				},
+			},
+			Error: "constraints not satisfiable:\nat most 1 of x, y may be selected\nx is mandatory\ny is mandatory",
+		},
+		{
+			Name: "global mutually exclusive constraint appears in conflicts",
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{
+					variable("x", constraint.Mandatory()),
+					variable("y"),
+					variable("z", constraint.Mandatory()),
+				},
+				Constraints: []deppy.Constraint{
+					constraint.MutuallyExclusive("x", "y", "z"),
+				},
+			},
+			Error: "constraints not satisfiable:\nat most one of x, y, z may be selected\nx is mandatory\nz is mandatory",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			s, err := New()
+			require.NoError(t, err)
+			installed, err := s.SolveProblem(tt.Problem)
+			if tt.Error != "" {
+				var ns deppy.NotSatisfiable
+				require.ErrorAs(t, err, &ns)
+				sort.Slice(ns, func(i, j int) bool {
+					return ns[i].String() < ns[j].String()
+				})
+				assert.EqualError(t, ns, tt.Error)
+				return
+			}
+			require.NoError(t, err)
+			var ids []deppy.Identifier
+			for _, v := range installed {
+				ids = append(ids, v.Identifier())
+			}
+			assert.Equal(t, tt.Installed, ids)
+		})
+	}
+}
+
+func TestSolveRejectsInvalidConstraints(t *testing.T) {
+	overflowing := constraint.WeightedAtMost(1, map[deppy.Identifier]int{"x": math.MaxInt, "y": 1})
+
+	s, err := New()
+	require.NoError(t, err)
+	_, err = s.Solve([]deppy.Variable{variable("x", overflowing), variable("y")})
+	assert.EqualError(t, err, `invalid constraint on "x": weights and limit overflow at x (9223372036854775807)`)
+
+	_, err = s.SolveProblem(deppy.Problem{
+		Variables:   []deppy.Variable{variable("x"), variable("y")},
+		Constraints: []deppy.Constraint{overflowing},
+	})
+	assert.EqualError(t, err, "invalid global constraint: weights and limit overflow at x (9223372036854775807)")
+
+	inc, err := s.Incremental([]deppy.Variable{variable("x"), variable("y")})
+	require.NoError(t, err)
+	assert.EqualError(t, inc.AddConstraints(overflowing), "invalid global constraint: weights and limit overflow at x (9223372036854775807)")
+	_, err = inc.Solve()
+	assert.NoError(t, err)
+}
+
+func TestSolveRejectsGlobalConstraintsOnSubject(t *testing.T) {
+	s, err := New()
+	require.NoError(t, err)
+	variables := []deppy.Variable{variable("x"), variable("y")}
+
+	_, err = s.SolveProblem(deppy.Problem{
+		Variables:   variables,
+		Constraints: []deppy.Constraint{constraint.AtMost(1, "x", "y"), constraint.Mandatory()},
+	})
+	assert.EqualError(t, err, `invalid global constraint "is mandatory" (*constraint.MandatoryConstraint): depends on its subject`)
+
+	_, err = s.SolveProblem(deppy.Problem{
+		Variables:   variables,
+		Constraints: []deppy.Constraint{constraint.WithMetadata(constraint.Dependency("x"), deppy.Metadata{Origin: "user"})},
+	})
+	assert.ErrorContains(t, err, `invalid global constraint "requires at least one of x"`)
+
+	inc, err := s.Incremental(variables, constraint.AtLeast(1, "x", "y"))
+	require.NoError(t, err)
+	assert.ErrorContains(t, inc.AddConstraints(constraint.AtMost(1, "x", "y"), constraint.Conflict("x")), `invalid global constraint "conflicts with x"`)
+	_, err = inc.Solve(constraint.Implies("y"))
+	assert.ErrorContains(t, err, "depends on its subject")
+	// the rejected batch is dropped as a whole, AtMost included
+	installed, err := inc.Solve(constraint.AtLeast(2, "x", "y"))
+	require.NoError(t, err)
+	assert.Len(t, installed, 2)
+}
+
+func TestGlobalConstraintJSON(t *testing.T) {
+	b, err := json.Marshal(deppy.AppliedConstraint{Constraint: constraint.AtMost(1, "x", "y")})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"constraint": "at most 1 of x, y may be selected"}`, string(b))
+}
+
+func TestResolve(t *testing.T) {
+	type dropped struct {
+		Subject   deppy.Identifier
+		Conflicts []string
+	}
+
+	type tc struct {
+		Name      string
+		Variables []deppy.Variable
+		Installed []deppy.Identifier
+		Dropped   []dropped
+		Error     error
+	}
+
+	for _, tt := range []tc{
+		{
+			Name: "satisfiable optional anchors are installed",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Optional(1), constraint.Dependency("x")),
+				variable("b", constraint.Optional(1)),
+				variable("x"),
+			},
+			Installed: []deppy.Identifier{"a", "b", "x"},
+		},
+		{
+			Name: "unsatisfiable optional anchor is dropped",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Optional(1), constraint.Dependency("x")),
+				variable("b", constraint.Optional(1)),
+				variable("x", constraint.Prohibited()),
+			},
+			Installed: []deppy.Identifier{"b"},
+			Dropped: []dropped{
+				{
+					Subject: "a",
+					Conflicts: []string{
+						"a is requested with priority 1",
+						"a requires at least one of x",
+						"x is ProhibitedConstraint",
+					},
+				},
+			},
+		},
+		{
+			Name: "higher priority anchor is kept",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Optional(1), constraint.Conflict("b")),
+				variable("b", constraint.Optional(2)),
+			},
+			Installed: []deppy.Identifier{"b"},
+			Dropped: []dropped{
+				{
+					Subject: "a",
+					Conflicts: []string{
+						"a conflicts with b",
+						"a is requested with priority 1",
+						"b is requested with priority 2",
+					},
+				},
+			},
+		},
+		{
+			Name: "earlier anchor is kept among equal priorities",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Optional(1), constraint.Conflict("b")),
+				variable("b", constraint.Optional(1)),
+			},
+			Installed: []deppy.Identifier{"a"},
+			Dropped: []dropped{
+				{
+					Subject: "b",
+					Conflicts: []string{
+						"a conflicts with b",
+						"a is requested with priority 1",
+						"b is requested with priority 1",
+					},
+				},
+			},
+		},
+		{
+			Name: "mandatory anchors take precedence",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Optional(100), constraint.Conflict("b")),
+				variable("b", constraint.Mandatory()),
+			},
+			Installed: []deppy.Identifier{"b"},
+			Dropped: []dropped{
+				{
+					Subject: "a",
+					Conflicts: []string{
+						"a conflicts with b",
+						"a is requested with priority 100",
+						"b is mandatory",
+					},
+				},
+			},
+		},
+		{
+			Name: "satisfiable recommendation is installed",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Recommends("x", "y")),
+				variable("x"),
+				variable("y"),
+			},
+			Installed: []deppy.Identifier{"a", "x"},
+		},
+		{
+			Name: "recommendation follows its order",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Recommends("y", "x")),
+				variable("x"),
+				variable("y"),
+			},
+			Installed: []deppy.Identifier{"a", "y"},
+		},
+		{
+			Name: "unsatisfiable recommendation is dropped",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("c", "b")),
+				variable("b"),
+				variable("c", constraint.Recommends("x")),
+				variable("x", constraint.Prohibited()),
+			},
+			Installed: []deppy.Identifier{"a", "c"},
+			Dropped: []dropped{
+				{
+					Subject: "c",
+					Conflicts: []string{
+						"c recommends at least one of x",
+						"x is ProhibitedConstraint",
+					},
+				},
+			},
+		},
+		{
+			Name: "recommendation of unselected variable is not reported",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory()),
+				variable("b", constraint.Recommends("x")),
+				variable("x", constraint.Prohibited()),
+			},
+			Installed: []deppy.Identifier{"a"},
+		},
+		{
+			Name: "optional anchors take precedence over recommendations",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Recommends("x")),
+				variable("b", constraint.Optional(0), constraint.Conflict("x")),
+				variable("x"),
+			},
+			Installed: []deppy.Identifier{"a", "b"},
+			Dropped: []dropped{
+				{
+					Subject: "a",
+					Conflicts: []string{
+						"a is mandatory",
+						"a recommends at least one of x",
+						"b conflicts with x",
+						"b is requested with priority 0",
+					},
+				},
+			},
+		},
+		{
+			Name: "optional anchors do not relax mandatory ones",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Optional(1)),
+				variable("b", constraint.Mandatory(), constraint.Dependency("x")),
+				variable("x", constraint.Prohibited()),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("b", constraint.Mandatory(), constraint.Dependency("x")),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("b", constraint.Mandatory(), constraint.Dependency("x")),
+					Constraint: constraint.Dependency("x"),
+				},
+				{
+					Variable:   variable("x", constraint.Prohibited()),
+					Constraint: constraint.Prohibited(),
+				},
+			},
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			s, err := New()
+			require.NoError(t, err)
+			result, err := s.Resolve(deppy.Problem{Variables: tt.Variables})
+			if tt.Error != nil {
+				var ns deppy.NotSatisfiable
+				require.ErrorAs(t, err, &ns)
+				assert.ElementsMatch(t, tt.Error, ns)
+				return
+			}
+			require.NoError(t, err)
+
+			var ids []deppy.Identifier
+			for _, v := range result.Selection {
+				ids = append(ids, v.Identifier())
+			}
+			assert.Equal(t, tt.Installed, ids)
+
+			var ds []dropped
+			for _, d := range result.Dropped {
+				var conflicts []string
+				for _, a := range d.Conflicts {
+					conflicts = append(conflicts, a.String())
+				}
+				sort.Strings(conflicts)
+				ds = append(ds, dropped{Subject: d.Constraint.Subject(), Conflicts: conflicts})
+			}
+			assert.Equal(t, tt.Dropped, ds)
+
+			selection, err := s.Solve(tt.Variables)
+			require.NoError(t, err)
+			assert.Equal(t, result.Selection, selection)
+		})
+	}
+}
+
+func TestSolvePreference(t *testing.T) {
+	// preferStable prefers Identifiers of the stable channel over
+	// any others.
+	preferStable := func(a, b deppy.Identifier) bool {
+		return strings.HasSuffix(string(a), "-stable") && !strings.HasSuffix(string(b), "-stable")
+	}
+	// preferLast prefers Identifiers that sort later.
+	preferLast := func(a, b deppy.Identifier) bool {
+		return a > b
+	}
+
+	type tc struct {
+		Name       string
+		Preference func(a, b deppy.Identifier) bool
+		Problem    deppy.Problem
+		Installed  []deppy.Identifier
+	}
+
+	for _, tt := range []tc{
+		{
+			Name: "without preference, order is followed",
+			Problem: deppy.Problem{Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("b-beta", "b-stable")),
+				variable("b-beta"),
+				variable("b-stable"),
+			}},
+			Installed: []deppy.Identifier{"a", "b-beta"},
+		},
+		{
+			Name:       "preference overrides order",
+			Preference: preferStable,
+			Problem: deppy.Problem{Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("b-beta", "b-stable")),
+				variable("b-beta"),
+				variable("b-stable"),
+			}},
+			Installed: []deppy.Identifier{"a", "b-stable"},
+		},
+		{
+			Name:       "preference applies to every dependency",
+			Preference: preferStable,
+			Problem: deppy.Problem{Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("c-beta", "c-stable")),
+				variable("b", constraint.Mandatory(), constraint.Dependency("c-stable", "c-beta")),
+				variable("c-beta", constraint.Dependency("d-beta", "d-stable")),
+				variable("c-stable", constraint.Dependency("d-beta", "d-stable")),
+				variable("d-beta"),
+				variable("d-stable"),
+			}},
+			Installed: []deppy.Identifier{"a", "b", "c-stable", "d-stable"},
+		},
+		{
+			Name:       "order breaks ties between equally preferred variables",
+			Preference: preferStable,
+			Problem: deppy.Problem{Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("b-beta", "b-alpha")),
+				variable("b-alpha"),
+				variable("b-beta"),
+			}},
+			Installed: []deppy.Identifier{"a", "b-beta"},
+		},
+		{
+			Name:       "preference applies to global constraints",
+			Preference: preferLast,
+			Problem: deppy.Problem{
+				Variables: []deppy.Variable{variable("x"), variable("y"), variable("z")},
+				Constraints: []deppy.Constraint{
+					constraint.AtLeast(1, "x", "y", "z"),
+				},
+			},
+			Installed: []deppy.Identifier{"z"},
+		},
+		{
+			Name:       "preference decides between minimal solutions",
+			Preference: preferLast,
+			Problem: deppy.Problem{Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Expr(expr.Implies{
+					If:   expr.Subject{},
+					Then: expr.Not{Operand: expr.And{expr.Not{Operand: expr.Var("x")}, expr.Not{Operand: expr.Var("y")}}},
+				})),
+				variable("x"),
+				variable("y"),
+			}},
+			Installed: []deppy.Identifier{"a", "y"},
+		},
+		{
+			Name: "other preference decides between minimal solutions",
+			Preference: func(a, b deppy.Identifier) bool {
+				return a < b
+			},
+			Problem: deppy.Problem{Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Expr(expr.Implies{
+					If:   expr.Subject{},
+					Then: expr.Not{Operand: expr.And{expr.Not{Operand: expr.Var("x")}, expr.Not{Operand: expr.Var("y")}}},
+				})),
+				variable("x"),
+				variable("y"),
+			}},
+			Installed: []deppy.Identifier{"a", "x"},
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var options []Option
+			if tt.Preference != nil {
+				options = append(options, WithPreference(tt.Preference))
+			}
+			s, err := New(options...)
+			require.NoError(t, err)
+			installed, err := s.SolveProblem(tt.Problem)
+			require.NoError(t, err)
+			var ids []deppy.Identifier
+			for _, v := range installed {
+				ids = append(ids, v.Identifier())
+			}
+			assert.Equal(t, tt.Installed, ids)
+		})
+	}
+}